@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/auth"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
 	"github.com/kvnyijia/bank-app/token"
 	pq "github.com/lib/pq"
@@ -68,7 +70,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
+	if !auth.CanRead(authPayload, account.Owner) {
 		err := errors.New("account does't belong to the authenticated user")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
@@ -77,8 +79,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 }
 
 type ListAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	pageRequest
 }
 
 func (server *Server) listAccount(ctx *gin.Context) {
@@ -89,17 +90,32 @@ func (server *Server) listAccount(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scope := "accounts:" + authPayload.Username
+	after, valid := server.pageCursor(ctx, req.pageRequest, scope)
+	if !valid {
+		return
+	}
+
 	arg := db.ListAccountsParams{
-		Owner:  authPayload.Username,
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+		Owner:          authPayload.Username,
+		AfterCreatedAt: after.NullCreatedAt(),
+		AfterID:        after.NullID(),
+		Limit:          req.limit() + 1,
 	}
 
-	account, err := server.store.ListAccounts(ctx, arg)
+	accounts, err := server.store.ListAccounts(ctx, arg)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	rsp, err := newListResponse(server.cursorSigner, accounts, req.limit(), scope, func(account db.Account) (time.Time, int64) {
+		return account.CreatedAt, account.ID
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
 }
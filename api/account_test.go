@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
 	mockdb "github.com/kvnyijia/bank-app/db/mock"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pagination"
 	"github.com/kvnyijia/bank-app/token"
 	"github.com/kvnyijia/bank-app/util"
 	"github.com/stretchr/testify/require"
@@ -34,9 +36,11 @@ func TestGetAccount(t *testing.T) {
 			name:      "OK",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+
 				// Build stubs for mock store, which we only care about GetAcount(), which is the only methods will be used by /accounts handler
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -52,9 +56,11 @@ func TestGetAccount(t *testing.T) {
 			name:      "UnauthorizedUser",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, "unauthorized_user", time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, "unauthorized_user")
+
 				// Build stubs for mock store, which we only care about GetAcount(), which is the only methods will be used by /accounts handler
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -65,6 +71,25 @@ func TestGetAccount(t *testing.T) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name:      "BankerViewsOtherAccount",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "banker", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, "banker")
+
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
 		{
 			name:      "NoAuthorization",
 			accountID: account.ID,
@@ -85,9 +110,11 @@ func TestGetAccount(t *testing.T) {
 			name:      "NotFound",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+
 				// Build stubs for mock store, which we only care about GetAcount(), which is the only methods will be used by /accounts handler
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -102,9 +129,11 @@ func TestGetAccount(t *testing.T) {
 			name:      "InternalError",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+
 				// Build stubs for mock store, which we only care about GetAcount(), which is the only methods will be used by /accounts handler
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -119,9 +148,11 @@ func TestGetAccount(t *testing.T) {
 			name:      "InvalidID",
 			accountID: 0, // Cuz the min ID should be 1
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+
 				// Build stubs for mock store, which we only care about GetAcount(), which is the only methods will be used by /accounts handler
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Any()).
@@ -178,3 +209,92 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	require.NoError(t, err)
 	require.Equal(t, account, gotAccount)
 }
+
+func TestListAccountsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 3
+	accounts := make([]db.Account, n)
+	for i := range accounts {
+		accounts[i] = randomAccount(user.Username)
+		accounts[i].CreatedAt = time.Now().UTC().Truncate(time.Microsecond).Add(time.Duration(i) * time.Second)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	buildSessionStub(store, user.Username)
+	buildSessionStub(store, user.Username)
+
+	firstPage := db.ListAccountsParams{
+		Owner: user.Username,
+		Limit: 3,
+	}
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(firstPage)).Times(1).Return(accounts, nil)
+
+	// The second page starts right after the last account of the first one
+	secondPage := db.ListAccountsParams{
+		Owner:          user.Username,
+		AfterCreatedAt: sql.NullTime{Time: accounts[1].CreatedAt, Valid: true},
+		AfterID:        sql.NullInt64{Int64: accounts[1].ID, Valid: true},
+		Limit:          3,
+	}
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(secondPage)).Times(1).Return(accounts[2:], nil)
+
+	server := newTestServer(t, store)
+
+	listAccounts := func(query string) listResponse[db.Account] {
+		recorder := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/accounts?"+query, nil)
+		require.NoError(t, err)
+
+		addAuth(t, req, server.tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+		server.router.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var rsp listResponse[db.Account]
+		err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+		require.NoError(t, err)
+		return rsp
+	}
+
+	rsp := listAccounts("page_size=2")
+	require.Equal(t, accounts[:2], rsp.Items)
+	require.NotEmpty(t, rsp.NextCursor)
+
+	rsp = listAccounts("page_size=2&cursor=" + rsp.NextCursor)
+	require.Equal(t, accounts[2:], rsp.Items)
+	require.Empty(t, rsp.NextCursor)
+}
+
+func TestListAccountsAPIInvalidRequest(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).AnyTimes().Return(db.GetAuthSessionRow{Session: db.Session{
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}}, nil)
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+
+	// A cursor of another user's listing is rejected
+	otherCursor, err := server.cursorSigner.Encode(pagination.Cursor{Scope: "accounts:" + other.Username, ID: 1})
+	require.NoError(t, err)
+
+	for _, query := range []string{"page_size=0&cursor=invalid", "page_size=101", "cursor=" + otherCursor} {
+		recorder := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/accounts?"+query, nil)
+		require.NoError(t, err)
+
+		addAuth(t, req, server.tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+		server.router.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusBadRequest, recorder.Code, query)
+	}
+}
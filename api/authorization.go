@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/auth"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+)
+
+// requireRoles only lets requests of the given roles through, so it must run after authMiddleware
+func requireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !auth.HasRole(authPayload, roles...) {
+			err := fmt.Errorf("role %s is not allowed to access this resource", authPayload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+var errEmailNotVerified = errors.New("email must be verified first")
+
+// requireVerifiedEmail only lets users who verified their email through, if required is set.
+// It must run after authMiddleware.
+func requireVerifiedEmail(store db.Store, required bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !required {
+			ctx.Next()
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if !user.IsEmailVerified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponseWithCode(codeEmailNotVerified, errEmailNotVerified))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+var errStepUpRequired = errors.New("a recent authentication is required, step up first")
+
+// requireStepUp only lets transfers of at least the threshold of their currency through if the user logged in
+// or stepped up within the max age. It must run after authMiddleware.
+func requireStepUp(thresholds map[string]int64, maxAge time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if len(thresholds) == 0 {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body)) // Let the handler read the body again
+
+		var req struct {
+			Amount   int64  `json:"amount"`
+			Currency string `json:"currency"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			ctx.Next() // The handler responds to invalid bodies
+			return
+		}
+
+		threshold, ok := thresholds[req.Currency]
+		if !ok || req.Amount < threshold {
+			ctx.Next()
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if time.Since(authPayload.AuthTime) > maxAge {
+			// As RFC 9470 has it, so clients can tell it from an invalid token
+			ctx.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, int(maxAge.Seconds())))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponseWithCode(codeStepUpRequired, errStepUpRequired))
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRoles(t *testing.T) {
+	testCases := []struct {
+		name         string
+		role         string
+		expectedCode int
+	}{
+		{
+			name:         "Admin",
+			role:         util.AdminRole,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Banker",
+			role:         util.BankerRole,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Depositor",
+			role:         util.DepositorRole,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildSessionStub(store, "user")
+
+			server := newTestServer(t, store)
+
+			authPath := "/admin"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.sessions),
+				requireRoles(util.AdminRole),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addAuth(t, req, server.tokenMaker, auth.AuthorizationTypeBearer, "user", tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	testCases := []struct {
+		name          string
+		required      bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "NotRequired",
+			required: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Verified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.User{Username: "user", IsEmailVerified: true}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotVerified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.User{Username: "user"}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+				var rsp gin.H
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, codeEmailNotVerified, rsp["code"])
+			},
+		},
+		{
+			name:     "InternalError",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildSessionStub(store, "user")
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			authPath := "/verified"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.sessions),
+				requireVerifiedEmail(server.store, tc.required),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addAuth(t, req, server.tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRequireStepUp(t *testing.T) {
+	thresholds := map[string]int64{util.USD: 1000, util.EUR: 1000}
+
+	testCases := []struct {
+		name          string
+		authTime      time.Time
+		body          string
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "RecentAuth",
+			authTime: time.Now().Add(-time.Minute),
+			body:     `{"amount": 1000, "currency": "USD"}`,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				// The handler still gets the whole body
+				require.JSONEq(t, `{"amount": 1000}`, recorder.Body.String())
+			},
+		},
+		{
+			name:     "StaleAuth",
+			authTime: time.Now().Add(-10 * time.Minute),
+			body:     `{"amount": 1000, "currency": "USD"}`,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, `Bearer error="insufficient_user_authentication", max_age=300`, recorder.Header().Get("WWW-Authenticate"))
+
+				var rsp gin.H
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, codeStepUpRequired, rsp["code"])
+			},
+		},
+		{
+			name: "NoAuthTime",
+			body: `{"amount": 5000, "currency": "EUR"}`,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "BelowThreshold",
+			body: `{"amount": 999, "currency": "USD"}`,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NoThreshold",
+			body: `{"amount": 1000000, "currency": "CAD"}`,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidBody",
+			body: `amount=1000000`,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildSessionStub(store, "user")
+
+			server := newTestServer(t, store)
+
+			authPath := "/stepped_up"
+			server.router.POST(
+				authPath,
+				authMiddleware(server.sessions),
+				requireStepUp(thresholds, 5*time.Minute),
+				func(ctx *gin.Context) {
+					var req struct {
+						Amount int64 `json:"amount" binding:"required"`
+					}
+					if err := ctx.ShouldBindJSON(&req); err != nil {
+						ctx.JSON(http.StatusBadRequest, errorResponse(err))
+						return
+					}
+					ctx.JSON(http.StatusOK, gin.H{"amount": req.Amount})
+				},
+			)
+
+			accessToken, _, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+				Username: "user",
+				Role:     util.DepositorRole,
+				Duration: time.Minute,
+				AuthTime: tc.authTime,
+				AMR:      []string{token.AMRPassword},
+			})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, authPath, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			req.Header.Set(authorizationHeaderKey, auth.AuthorizationTypeBearer+" "+accessToken)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(recorder)
+		})
+	}
+}
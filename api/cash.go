@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/auth"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+)
+
+type createDepositRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,currency"`
+	Source   string `json:"source" binding:"required,max=255"`
+}
+
+var errOwnDeposit = errors.New("staff can't record deposits to their own accounts")
+
+// createDeposit credits any account with money a banker took in. The account doesn't have to be theirs,
+// and mustn't be, so no one can create money for themselves.
+func (server *Server) createDeposit(ctx *gin.Context) {
+	var uri GetAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req createDepositRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner == authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errOwnDeposit))
+		return
+	}
+
+	arg := db.DepositTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+		Source:    req.Source,
+	}
+
+	result, err := server.store.DepositTx(ctx, arg)
+	if err != nil {
+		txErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type createWithdrawalRequest struct {
+	Amount      int64  `json:"amount" binding:"required,gt=0"`
+	Currency    string `json:"currency" binding:"required,currency"`
+	Destination string `json:"destination" binding:"required,max=255"`
+}
+
+func (server *Server) createWithdrawal(ctx *gin.Context) {
+	var uri GetAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req createWithdrawalRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.validOwnAccount(ctx, uri.ID, req.Currency) {
+		return
+	}
+
+	arg := db.WithdrawTxParams{
+		AccountID:   uri.ID,
+		Amount:      req.Amount,
+		Destination: req.Destination,
+	}
+
+	result, err := server.store.WithdrawTx(ctx, arg)
+	if err != nil {
+		txErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// validOwnAccount checks that the account exists in the currency and belongs to the authenticated user
+func (server *Server) validOwnAccount(ctx *gin.Context, accountID int64, currency string) bool {
+	account, valid := server.validAccount(ctx, accountID, currency)
+	if !valid {
+		return false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !auth.CanWrite(authPayload, account.Owner) {
+		err := errors.New("account does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+	return true
+}
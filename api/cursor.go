@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/pagination"
+)
+
+const defaultPageSize = 20
+
+// pageRequest is bound from the query of every listing. Without a cursor the first page is returned.
+type pageRequest struct {
+	Cursor   string `form:"cursor"`
+	PageSize int32  `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+func (req pageRequest) limit() int32 {
+	if req.PageSize == 0 {
+		return defaultPageSize
+	}
+	return req.PageSize
+}
+
+// listResponse is the envelope of every listing. NextCursor is empty on the last page.
+type listResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// pageCursor decodes the cursor of the request, or writes the error response. It's nil for the first page.
+func (server *Server) pageCursor(ctx *gin.Context, req pageRequest, scope string) (*pagination.Cursor, bool) {
+	c, err := server.cursorSigner.Decode(req.Cursor, scope)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+	return c, true
+}
+
+// newListResponse wraps a page fetched with one extra row
+func newListResponse[T any](
+	signer *pagination.CursorSigner,
+	items []T,
+	limit int32,
+	scope string,
+	key func(item T) (time.Time, int64),
+) (listResponse[T], error) {
+	items, next, err := pagination.Paginate(signer, items, limit, scope, key)
+	return listResponse[T]{Items: items, NextCursor: next}, err
+}
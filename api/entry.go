@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/auth"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+)
+
+type listEntriesRequest struct {
+	historyFilter
+	pageRequest
+}
+
+// listEntries shows the balance changes of an account, newest first
+func (server *Server) listEntries(ctx *gin.Context) {
+	var uri GetAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listEntriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.findAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !auth.CanRead(authPayload, account.Owner) {
+		err := errors.New("account does't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	scope := fmt.Sprintf("entries:%d", account.ID)
+	before, valid := server.pageCursor(ctx, req.pageRequest, scope)
+	if !valid {
+		return
+	}
+
+	arg := db.ListAccountEntriesParams{
+		AccountID:       account.ID,
+		StartTime:       req.startTime(),
+		EndTime:         req.endTime(),
+		Direction:       req.direction(),
+		MinAmount:       req.minAmount(),
+		MaxAmount:       req.maxAmount(),
+		BeforeCreatedAt: before.NullCreatedAt(),
+		BeforeID:        before.NullID(),
+		Limit:           req.limit() + 1,
+	}
+
+	entries, err := server.store.ListAccountEntries(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := newListResponse(server.cursorSigner, entries, req.limit(), scope, func(entry db.Entry) (time.Time, int64) {
+		return entry.CreatedAt, entry.ID
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/fx"
+)
+
+type quoteRequest struct {
+	FromCurrency string `form:"from_currency" binding:"required,currency"`
+	ToCurrency   string `form:"to_currency" binding:"required,currency"`
+	Amount       int64  `form:"amount" binding:"required,gt=0"`
+}
+
+type quoteResponse struct {
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	ExchangeRate string `json:"exchange_rate"`
+	Amount       int64  `json:"amount"`
+	ToAmount     int64  `json:"to_amount"`
+}
+
+// getQuote tells how much a transfer would credit at the current rate.
+// The rate is not locked in, a later transfer uses the rate at that time.
+func (server *Server) getQuote(ctx *gin.Context) {
+	var req quoteRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rate, toAmount, valid := server.convert(ctx, req.FromCurrency, req.ToCurrency, req.Amount)
+	if !valid {
+		return
+	}
+
+	rsp := quoteResponse{
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		ExchangeRate: rate.Value,
+		Amount:       req.Amount,
+		ToAmount:     toAmount,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// convert looks up the current rate and converts the amount, or writes the error response
+func (server *Server) convert(ctx *gin.Context, from string, to string, amount int64) (fx.Rate, int64, bool) {
+	if server.rateProvider == nil {
+		err := errors.New("currency exchange is not available")
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(err))
+		return fx.Rate{}, 0, false
+	}
+
+	rate, err := server.rateProvider.GetRate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return rate, 0, false
+		}
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return rate, 0, false
+	}
+
+	toAmount, err := rate.Convert(amount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return rate, 0, false
+	}
+
+	if toAmount <= 0 {
+		err := fmt.Errorf("amount %d %s is too small to convert to %s", amount, from, to)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return rate, 0, false
+	}
+	return rate, toAmount, true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/db/migration"
+	"github.com/kvnyijia/bank-app/token"
+)
+
+// Probes are called every few seconds, so they stay out of the auth middleware & the request log
+var healthPaths = []string{"/healthz", "/readyz"}
+
+const readinessTimeout = 2 * time.Second
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+type healthCheck struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Version uint   `json:"version,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+// getHealthz only tells the process is alive and serving reqs
+func (server *Server) getHealthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, healthResponse{Status: statusOK})
+}
+
+// getReadyz tells whether the server can handle reqs, i.e. the db is up, fully migrated, and tokens can be made
+func (server *Server) getReadyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]healthCheck{
+		"database":   server.checkDatabase(checkCtx),
+		"migrations": server.checkMigrations(checkCtx),
+		"token_key":  server.checkTokenKey(),
+	}
+
+	rsp := healthResponse{Status: statusOK, Checks: checks}
+	code := http.StatusOK
+	for _, check := range checks {
+		if check.Status != statusOK {
+			rsp.Status = statusUnavailable
+			code = http.StatusServiceUnavailable
+		}
+	}
+	ctx.JSON(code, rsp)
+}
+
+func failedCheck(err error) healthCheck {
+	return healthCheck{Status: statusUnavailable, Error: err.Error()}
+}
+
+func (server *Server) checkDatabase(ctx context.Context) healthCheck {
+	if err := server.store.Ping(ctx); err != nil {
+		return failedCheck(err)
+	}
+	return healthCheck{Status: statusOK}
+}
+
+func (server *Server) checkMigrations(ctx context.Context) healthCheck {
+	version, dirty, err := server.store.MigrationVersion(ctx)
+	if err != nil {
+		return failedCheck(err)
+	}
+
+	check := healthCheck{Status: statusOK, Version: version}
+	if err := migration.CheckVersion(version, dirty); err != nil {
+		check.Status = statusUnavailable
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// checkTokenKey makes sure a token signed by the key can be verified again
+func (server *Server) checkTokenKey() healthCheck {
+	accessToken, _, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username: "readyz",
+		Duration: time.Minute,
+	})
+	if err != nil {
+		return failedCheck(err)
+	}
+
+	if _, err := server.tokenMaker.VerifyToken(accessToken); err != nil {
+		return failedCheck(err)
+	}
+	return healthCheck{Status: statusOK}
+}
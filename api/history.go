@@ -0,0 +1,36 @@
+package api
+
+import (
+	"database/sql"
+	"time"
+)
+
+// historyFilter holds the filters shared by the entry & transfer listings. Zero values mean no filter.
+// Amounts are compared in the currency of the account whose history is listed.
+type historyFilter struct {
+	StartTime time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00" binding:"omitempty,gtfield=StartTime"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+	MinAmount int64     `form:"min_amount" binding:"omitempty,min=1"`
+	MaxAmount int64     `form:"max_amount" binding:"omitempty,min=1,gtefield=MinAmount"`
+}
+
+func (filter historyFilter) startTime() sql.NullTime {
+	return sql.NullTime{Time: filter.StartTime, Valid: !filter.StartTime.IsZero()}
+}
+
+func (filter historyFilter) endTime() sql.NullTime {
+	return sql.NullTime{Time: filter.EndTime, Valid: !filter.EndTime.IsZero()}
+}
+
+func (filter historyFilter) direction() sql.NullString {
+	return sql.NullString{String: filter.Direction, Valid: len(filter.Direction) > 0}
+}
+
+func (filter historyFilter) minAmount() sql.NullInt64 {
+	return sql.NullInt64{Int64: filter.MinAmount, Valid: filter.MinAmount > 0}
+}
+
+func (filter historyFilter) maxAmount() sql.NullInt64 {
+	return sql.NullInt64{Int64: filter.MaxAmount, Valid: filter.MaxAmount > 0}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+	// How long storing the response may take, even once the client is gone
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// idempotencyMiddleware makes a POST safe to retry: the first request with a given Idempotency-Key runs the handler
+// and stores its response, and every retry with the same key and body gets the stored response back.
+// Keys are scoped to the authenticated user, so it must run after authMiddleware on protected routes.
+// A key can be used again once it's older than the ttl. A key still in progress after the abandon timeout is taken over
+// by a retry of the same request, so it must be longer than any request can take.
+func idempotencyMiddleware(store db.Store, ttl time.Duration, abandonTimeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+		if len(key) == 0 {
+			ctx.Next() // The header is optional
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			err := errors.New("idempotency key is too long")
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body)) // Let the handler read the body again
+
+		username := ""
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			username = payload.(*token.Payload).Username
+		}
+
+		requestHash := hashRequest(ctx.Request, body)
+		now := time.Now()
+		_, err = store.CreateIdempotencyKey(ctx, db.CreateIdempotencyKeyParams{
+			Username:        username,
+			Key:             key,
+			RequestHash:     requestHash,
+			ExpiredBefore:   now.Add(-ttl),
+			AbandonedBefore: now.Add(-abandonTimeout),
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// The key is already taken, so this is a retry
+				replayResponse(ctx, store, username, key, requestHash)
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		writer := &bodyRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+
+		completed := false
+		defer func() {
+			// Also runs when the handler panics, before the recovery further up turns it into a 500.
+			// The request ctx is done once the client gives up, which is when a retry needs the key the most.
+			storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+			defer cancel()
+
+			var err error
+			status := ctx.Writer.Status()
+			if !completed || status >= http.StatusInternalServerError {
+				// Nothing was committed, so release the key and let the client try again
+				err = store.DeleteIdempotencyKey(storeCtx, db.DeleteIdempotencyKeyParams{
+					Username: username,
+					Key:      key,
+				})
+			} else {
+				_, err = store.UpdateIdempotencyKeyResponse(storeCtx, db.UpdateIdempotencyKeyResponseParams{
+					Username:       username,
+					Key:            key,
+					ResponseStatus: int32(status),
+					ResponseBody:   writer.body.Bytes(),
+				})
+			}
+			if err != nil {
+				ctx.Error(err) // The response is already sent, so only record the error
+			}
+		}()
+
+		ctx.Next()
+		completed = true
+	}
+}
+
+func replayResponse(ctx *gin.Context, store db.Store, username string, key string, requestHash string) {
+	idempotencyKey, err := store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: username,
+		Key:      key,
+	})
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if idempotencyKey.RequestHash != requestHash {
+		err := errors.New("idempotency key was already used for a different request")
+		ctx.AbortWithStatusJSON(http.StatusConflict, errorResponseWithCode(codeIdempotencyKeyReused, err))
+		return
+	}
+
+	if idempotencyKey.ResponseStatus == 0 {
+		err := errors.New("a request with this idempotency key is still in progress")
+		ctx.AbortWithStatusJSON(http.StatusConflict, errorResponseWithCode(codeIdempotencyKeyInProgress, err))
+		return
+	}
+
+	ctx.Data(int(idempotencyKey.ResponseStatus), "application/json; charset=utf-8", idempotencyKey.ResponseBody) // Every stored response is JSON
+	ctx.Abort()
+}
+
+// hashRequest fingerprints the request, so a key can't be reused for another endpoint or body
+func hashRequest(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(req.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// bodyRecorder keeps a copy of the response body, so it can be stored for replays
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	idempotencyKeyTTL            = 24 * time.Hour
+	idempotencyKeyAbandonTimeout = time.Minute
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	path := "/idempotent"
+	key := "d6c6f0ae-0a54-4b0f-a3a1-3a3f1e3ff1e5"
+	body := []byte(`{"amount":10}`)
+
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	requestHash := hashRequest(req, body)
+
+	testCases := []struct {
+		name          string
+		key           string
+		handlerStatus int
+		handlerPanics bool
+		// The client gives up before the response is stored
+		cancelRequest bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int)
+	}{
+		{
+			name:          "NoKey",
+			key:           "",
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "FirstRequest",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateIdempotencyKeyParams) (db.IdempotencyKey, error) {
+						require.Equal(t, key, arg.Key)
+						require.Equal(t, requestHash, arg.RequestHash)
+						require.WithinDuration(t, time.Now().Add(-idempotencyKeyTTL), arg.ExpiredBefore, time.Second)
+						require.WithinDuration(t, time.Now().Add(-idempotencyKeyAbandonTimeout), arg.AbandonedBefore, time.Second)
+						return db.IdempotencyKey{}, nil
+					})
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Eq(db.UpdateIdempotencyKeyResponseParams{
+						Key:            key,
+						ResponseStatus: http.StatusOK,
+						ResponseBody:   []byte(`{"status":200}`),
+					})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "ClientGone",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			cancelRequest: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.UpdateIdempotencyKeyResponseParams) (db.IdempotencyKey, error) {
+						// Otherwise the response would never be stored, and every retry would find the key in progress
+						require.NoError(t, ctx.Err())
+						return db.IdempotencyKey{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "HandlerPanicked",
+			key:           key,
+			handlerPanics: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{Key: key})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "HandlerFailed",
+			key:           key,
+			handlerStatus: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{Key: key})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "Replay",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Key:            key,
+						RequestHash:    requestHash,
+						ResponseStatus: http.StatusCreated,
+						ResponseBody:   []byte(`{"replayed":true}`),
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, `{"replayed":true}`, recorder.Body.String())
+				require.Zero(t, handlerCalls)
+			},
+		},
+		{
+			name:          "DifferentRequest",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Key:            key,
+						RequestHash:    "another-hash",
+						ResponseStatus: http.StatusOK,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Zero(t, handlerCalls)
+			},
+		},
+		{
+			name:          "InProgress",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Key:         key,
+						RequestHash: requestHash,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Zero(t, handlerCalls)
+			},
+		},
+		{
+			name:          "InternalError",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Zero(t, handlerCalls)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			handlerCalls := 0
+			server.router.POST(
+				path,
+				idempotencyMiddleware(server.store, idempotencyKeyTTL, idempotencyKeyAbandonTimeout),
+				func(ctx *gin.Context) {
+					handlerCalls++
+					if tc.handlerPanics {
+						panic("handler failed")
+					}
+					ctx.JSON(tc.handlerStatus, gin.H{"status": tc.handlerStatus})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			reqCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelRequest {
+				cancel()
+			}
+
+			req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, path, bytes.NewReader(body))
+			require.NoError(t, err)
+			if len(tc.key) > 0 {
+				req.Header.Set(idempotencyKeyHeader, tc.key)
+			}
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder, handlerCalls)
+		})
+	}
+}
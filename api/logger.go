@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/rs/zerolog"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Bodies of failed reqs are logged to help debugging, up to this size
+const maxLoggedBodySize = 4 << 10
+
+// requestLogger tags every req with a request ID, which the logger in the req ctx carries to the store,
+// and logs the req once it's handled
+func requestLogger(logger zerolog.Logger, skipPaths []string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(ctx *gin.Context) {
+		requestID := logging.RequestID(ctx.GetHeader(requestIDHeader))
+		ctx.Header(requestIDHeader, requestID)
+
+		reqLogger := logging.ForRequest(ctx.Request.Context(), logger, requestID)
+		ctx.Request = ctx.Request.WithContext(reqLogger.WithContext(ctx.Request.Context()))
+
+		body := peekBody(ctx)
+		start := time.Now()
+		ctx.Next()
+
+		if skip[ctx.Request.URL.Path] {
+			return
+		}
+
+		// The auth middleware may have added the username to the logger of the req
+		logger := zerolog.Ctx(ctx.Request.Context())
+
+		status := ctx.Writer.Status()
+		event := logger.Info()
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Warn()
+		}
+
+		event.
+			Str("method", ctx.Request.Method).
+			Str("path", ctx.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", ctx.ClientIP())
+
+		if status >= 400 {
+			if redacted, ok := logging.RedactJSON(body); ok {
+				event.RawJSON("body", redacted)
+			}
+			if len(ctx.Errors) > 0 {
+				event.Str("errors", ctx.Errors.String())
+			}
+		}
+		event.Msg("handled request")
+	}
+}
+
+// peekBody reads the start of the req body without taking it away from the handler
+func peekBody(ctx *gin.Context) []byte {
+	if ctx.Request.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxLoggedBodySize))
+	if err != nil {
+		return nil
+	}
+	ctx.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), ctx.Request.Body), ctx.Request.Body}
+	return body
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	password := util.RandomString(6)
+
+	testCases := []struct {
+		name          string
+		requestID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, logs *bytes.Buffer)
+	}{
+		{
+			name:      "KeepRequestID",
+			requestID: "abc-123",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, logs *bytes.Buffer) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, "abc-123", recorder.Header().Get(requestIDHeader))
+
+				entry := lastLogEntry(t, logs)
+				require.Equal(t, "abc-123", entry[logging.RequestIDField])
+				require.Equal(t, "warn", entry["level"])
+				require.Equal(t, "/users/login", entry["path"])
+				require.EqualValues(t, http.StatusUnauthorized, entry["status"])
+
+				// The body of the failed req is logged, but never the password
+				require.NotContains(t, logs.String(), password)
+				body, ok := entry["body"].(map[string]any)
+				require.True(t, ok)
+				require.Equal(t, "[REDACTED]", body["password"])
+			},
+		},
+		{
+			name:      "GenerateRequestID",
+			requestID: "not a valid id!",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, logs *bytes.Buffer) {
+				requestID := recorder.Header().Get(requestIDHeader)
+				require.NotEmpty(t, requestID)
+				require.NotEqual(t, "not a valid id!", requestID)
+				require.Equal(t, requestID, lastLogEntry(t, logs)[logging.RequestIDField])
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			logs := &bytes.Buffer{}
+			config := util.Config{
+				TokenSymmetricKey:   util.RandomString(32),
+				CursorSigningKey:    util.RandomString(32),
+				TOTPEncryptionKey:   util.RandomString(32),
+				AccessTokenDuration: time.Minute,
+				FXRatesFile:         testRatesFile,
+			}
+			server, err := NewServer(config, store, ratelimit.NewMemoryStore(), zerolog.New(logs))
+			require.NoError(t, err)
+
+			data, err := json.Marshal(gin.H{
+				"username": util.RandomOwner(),
+				"password": password,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set(requestIDHeader, tc.requestID)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, logs)
+		})
+	}
+}
+
+func TestRequestLoggerSkipsHealthChecks(t *testing.T) {
+	logs := &bytes.Buffer{}
+	config := util.Config{TokenSymmetricKey: util.RandomString(32), CursorSigningKey: util.RandomString(32), TOTPEncryptionKey: util.RandomString(32), FXRatesFile: testRatesFile}
+	server, err := NewServer(config, nil, ratelimit.NewMemoryStore(), zerolog.New(logs))
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get(requestIDHeader))
+	require.Empty(t, logs.String())
+}
+
+func lastLogEntry(t *testing.T, logs *bytes.Buffer) map[string]any {
+	lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+	require.NotEmpty(t, lines[len(lines)-1])
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry))
+	return entry
+}
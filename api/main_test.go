@@ -7,17 +7,32 @@ import (
 
 	"github.com/gin-gonic/gin"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/ratelimit"
 	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
+const testRatesFile = "../fx/testdata/rates.json"
+
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:          util.RandomString(32),
+		CursorSigningKey:           util.RandomString(32),
+		AccessTokenDuration:        time.Minute,
+		RefreshTokenDuration:       time.Hour,
+		FXRatesFile:                testRatesFile,
+		LoginMaxAttempts:           3,
+		LoginLockout:               time.Minute,
+		LoginMaxLockout:            time.Hour,
+		PasswordResetURL:           "https://bank.example.com/reset-password",
+		PasswordResetTokenDuration: 30 * time.Minute,
+		TOTPEncryptionKey:          util.RandomString(32),
+		TOTPIssuer:                 "bank-app",
+		MFAChallengeDuration:       5 * time.Minute,
 	}
 
-	server, err := NewServer(config, store)
+	server, err := NewServer(config, store, ratelimit.NewMemoryStore(), zerolog.Nop())
 	require.NoError(t, err)
 	return server
 }
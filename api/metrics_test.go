@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	testCases := []struct {
+		name   string
+		urls   []string
+		route  string
+		status int
+	}{
+		{
+			name:   "Route",
+			urls:   []string{"/accounts/1", "/accounts/2"},
+			route:  "/accounts/:id",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Unmatched",
+			urls:   []string{"/" + util.RandomString(10), "/" + util.RandomString(10)},
+			route:  metrics.UnmatchedRoute,
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			histogram := metrics.HTTPRequestDuration.WithLabelValues(http.MethodGet, tc.route, strconv.Itoa(tc.status))
+			before := sampleCount(t, histogram)
+
+			for _, url := range tc.urls {
+				request, err := http.NewRequest(http.MethodGet, url, nil)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, "Bearer not-a-token")
+
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, tc.status, recorder.Code)
+			}
+
+			// Every req of the route lands in the same series
+			require.Equal(t, before+uint64(len(tc.urls)), sampleCount(t, histogram))
+		})
+	}
+}
+
+func TestTokenVerificationMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	counter := metrics.TokenVerificationFailures.WithLabelValues("invalid")
+	before := testutil.ToFloat64(counter)
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer not-a-token")
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
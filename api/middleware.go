@@ -2,51 +2,37 @@ package api
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/auth"
+	"github.com/kvnyijia/bank-app/logging"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
-	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
-		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header is not provided") // 401
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-			return
-		}
-
-		fields := strings.Fields(authorizationHeader)
-		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-			return
-		}
-
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-			return
-		}
+var (
+	errTokenBeforePasswordChange = auth.ErrTokenBeforePasswordChange
+	errNotRefreshToken           = errors.New("token is not a refresh token")
+)
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+func authMiddleware(sessions *auth.Sessions) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload, err := sessions.Authorize(ctx, ctx.GetHeader(authorizationHeaderKey))
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err)) // 401
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
 
 		ctx.Set(authorizationPayloadKey, payload)
+		logging.WithUsername(ctx.Request.Context(), payload.Username)
 		ctx.Next() // Forward the req to the next handler
 	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,7 +9,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
 	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,25 +24,46 @@ func addAuth(
 	tokenMaker token.Maker,
 	authType string,
 	username string,
+	role string,
 	duration time.Duration,
 ) {
-	token, err := tokenMaker.CreateToken(username, duration)
+	accessToken, payload, err := tokenMaker.CreateToken(token.CreateTokenParams{
+		Username: username,
+		Role:     role,
+		Duration: duration,
+	})
 	require.NoError(t, err)
+	require.NotEmpty(t, payload)
 
-	authHeader := fmt.Sprintf("%s %s", authType, token)
+	authHeader := fmt.Sprintf("%s %s", authType, accessToken)
 	req.Header.Set(authorizationHeaderKey, authHeader)
 }
 
+// buildSessionStub makes the auth middleware find an active session of the user
+func buildSessionStub(store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		GetAuthSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.GetAuthSessionRow{Session: db.Session{
+			Username:  username,
+			ExpiresAt: time.Now().Add(time.Minute),
+		}}, nil)
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, "user")
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -47,6 +74,9 @@ func TestAuthMiddleware(t *testing.T) {
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
 				// No auth
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code) // 401
 			},
@@ -54,7 +84,10 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "UnsupportedAuth",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, "unsupported", "user", time.Minute)
+				addAuth(t, req, tokenMaker, "unsupported", "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -63,7 +96,10 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthFormat",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, "", "user", time.Minute)
+				addAuth(t, req, tokenMaker, "", "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -72,23 +108,146 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuth(t, req, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MFAChallengeToken",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				mfaToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+					Username: "user",
+					Role:     util.DepositorRole,
+					Duration: time.Minute,
+					Purpose:  token.PurposeMFAChallenge,
+				})
+				require.NoError(t, err)
+				req.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", auth.AuthorizationTypeBearer, mfaToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				refreshToken, _ := createRefreshToken(t, tokenMaker, "user", time.Hour)
+				req.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", auth.AuthorizationTypeBearer, refreshToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{Session: db.Session{
+						Username:  "user",
+						IsBlocked: true,
+						ExpiresAt: time.Now().Add(time.Minute),
+					}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, "someone_else")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChanged",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{
+						Session: db.Session{
+							Username:  "user",
+							ExpiresAt: time.Now().Add(time.Minute),
+						},
+						PasswordChangedAt: time.Now().Add(time.Second),
+					}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		// Run subtests
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil) // We don't need to access dbstore so set it to nil
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
 
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.sessions),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{}) // Send 200 ok & empty body to the client
 				},
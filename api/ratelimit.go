@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/rs/zerolog"
+)
+
+var errRateLimited = errors.New("too many requests, try again later")
+
+// rateLimit responds 429 once the key of the req used up its bucket. Reqs without a key aren't limited.
+// Buckets of different limits are told apart by the name.
+func rateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key func(ctx *gin.Context) string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limit.Unlimited() {
+			ctx.Next()
+			return
+		}
+
+		value := key(ctx)
+		if value == "" {
+			ctx.Next()
+			return
+		}
+
+		result, err := store.Take(ctx, name+":"+value, limit)
+		if err != nil {
+			// Locking everyone out because the limiter is down would be worse than not limiting for a while
+			zerolog.Ctx(ctx.Request.Context()).Error().Err(err).Str("limit", name).Msg("cannot take from rate limit bucket")
+			ctx.Next()
+			return
+		}
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponseWithCode(codeRateLimited, errRateLimited))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func clientIPKey(ctx *gin.Context) string {
+	return ctx.ClientIP()
+}
+
+// loginUsernameKey is the username the req tries to log in as, so guesses spread over many IPs are limited as well
+func loginUsernameKey(ctx *gin.Context) string {
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(peekBody(ctx), &req); err != nil {
+		return ""
+	}
+	return req.Username
+}
+
+// mfaUsernameKey is the username of the MFA challenge the req exchanges, so codes are limited per user like passwords.
+// Reqs without a valid challenge are turned away by the handler anyway.
+func (server *Server) mfaUsernameKey(ctx *gin.Context) string {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if err := json.Unmarshal(peekBody(ctx), &req); err != nil {
+		return ""
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil || payload.Purpose != token.PurposeMFAChallenge {
+		return ""
+	}
+	return payload.Username
+}
+
+// authUsernameKey is the user the access token of the req belongs to, so no one can flood their own email
+func authUsernameKey(ctx *gin.Context) string {
+	authPayload, ok := ctx.Get(authorizationPayloadKey)
+	if !ok {
+		return ""
+	}
+	return authPayload.(*token.Payload).Username
+}
+
+// resetEmailKey is the email the req asks a reset link for, so no one can flood a user with emails
+func resetEmailKey(ctx *gin.Context) string {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(peekBody(ctx), &req); err != nil {
+		return ""
+	}
+	return strings.ToLower(req.Email)
+}
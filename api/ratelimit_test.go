@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginRateLimit(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name        string
+		config      util.Config
+		username    func(i int) string
+		clientIP    func(i int) string
+		allowedReqs int
+	}{
+		{
+			name:        "PerIP",
+			config:      util.Config{LoginRateLimitPerIP: 2, LoginRateLimitInterval: time.Minute},
+			username:    func(i int) string { return util.RandomOwner() },
+			clientIP:    func(i int) string { return "192.0.2.1" },
+			allowedReqs: 2,
+		},
+		{
+			name:        "PerUsername",
+			config:      util.Config{LoginRateLimitPerUsername: 3, LoginRateLimitInterval: time.Minute},
+			username:    func(i int) string { return username },
+			clientIP:    func(i int) string { return fmt.Sprintf("192.0.2.%d", i+1) },
+			allowedReqs: 3,
+		},
+		{
+			name:        "NoLimit",
+			config:      util.Config{},
+			username:    func(i int) string { return username },
+			clientIP:    func(i int) string { return "192.0.2.1" },
+			allowedReqs: 5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(tc.allowedReqs).
+				Return(db.User{}, sql.ErrNoRows)
+
+			tc.config.TokenSymmetricKey = util.RandomString(32)
+			tc.config.CursorSigningKey = util.RandomString(32)
+			tc.config.TOTPEncryptionKey = util.RandomString(32)
+			tc.config.FXRatesFile = testRatesFile
+			server, err := NewServer(tc.config, store, ratelimit.NewMemoryStore(), zerolog.Nop())
+			require.NoError(t, err)
+
+			for i := 0; i < tc.allowedReqs; i++ {
+				recorder := login(t, server, tc.username(i), tc.clientIP(i))
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			}
+
+			if tc.config.LoginRateLimitPerIP == 0 && tc.config.LoginRateLimitPerUsername == 0 {
+				return
+			}
+
+			// The limited req doesn't even get to the db
+			recorder := login(t, server, tc.username(tc.allowedReqs), tc.clientIP(tc.allowedReqs))
+			require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+			var rsp gin.H
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, codeRateLimited, rsp["code"])
+		})
+	}
+}
+
+func login(t *testing.T, server *Server, username string, clientIP string) *httptest.ResponseRecorder {
+	data, err := json.Marshal(gin.H{
+		"username": username,
+		"password": util.RandomString(6),
+	})
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	request.RemoteAddr = clientIP + ":12345"
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestLoginMFARateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := util.RandomOwner()
+	allowedReqs := 3
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(username)).
+		Times(allowedReqs).
+		Return(db.User{}, sql.ErrNoRows)
+
+	config := util.Config{
+		LoginRateLimitPerUsername: allowedReqs,
+		LoginRateLimitInterval:    time.Minute,
+		TokenSymmetricKey:         util.RandomString(32),
+		CursorSigningKey:          util.RandomString(32),
+		TOTPEncryptionKey:         util.RandomString(32),
+		FXRatesFile:               testRatesFile,
+	}
+	server, err := NewServer(config, store, ratelimit.NewMemoryStore(), zerolog.Nop())
+	require.NoError(t, err)
+
+	mfaLogin := func(mfaToken string, clientIP string) *httptest.ResponseRecorder {
+		data, err := json.Marshal(gin.H{
+			"mfa_token": mfaToken,
+			"code":      "123456",
+		})
+		require.NoError(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+		request.RemoteAddr = clientIP + ":12345"
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Codes are limited per user like passwords, even if each guess comes with a new challenge from a new IP
+	for i := 0; i <= allowedReqs; i++ {
+		mfaToken, _, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+			Username: username,
+			Duration: time.Minute,
+			Purpose:  token.PurposeMFAChallenge,
+		})
+		require.NoError(t, err)
+
+		recorder := mfaLogin(mfaToken, fmt.Sprintf("192.0.2.%d", i+1))
+		if i < allowedReqs {
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			continue
+		}
+		require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	}
+
+	// Reqs without a valid challenge have no user to count against, and are turned away anyway
+	recorder := mfaLogin("invalid-token", "192.0.2.100")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -1,69 +1,230 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/kvnyijia/bank-app/auth"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/doc"
+	"github.com/kvnyijia/bank-app/fx"
+	"github.com/kvnyijia/bank-app/pagination"
+	"github.com/kvnyijia/bank-app/ratelimit"
 	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/tracing"
 	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // Server serves HTTP reqs for our banking service
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config       util.Config
+	store        db.Store
+	tokenMaker   token.Maker
+	rateProvider fx.RateProvider // nil when currency exchange is not configured
+	cursorSigner *pagination.CursorSigner
+	rateLimiter  ratelimit.Store
+	totpCipher   *totp.Cipher
+	authChecker  *auth.Checker
+	sessions     *auth.Sessions
+	// Transfers of at least these amounts per currency need a recent authentication
+	stepUpThresholds map[string]int64
+	logger           zerolog.Logger
+	router           *gin.Engine
 }
 
 // Creates a new HTTP server and setup routing
-func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey) // Could also be NewJWTMaker()
+func NewServer(config util.Config, store db.Store, rateLimiter ratelimit.Store, logger zerolog.Logger) (*Server, error) {
+	tokenMaker, err := token.NewMakerFromConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	rateProvider, err := fx.NewRateProviderFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate provider: %w", err)
+	}
+
+	cursorSigner, err := pagination.NewCursorSigner(config.CursorSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create cursor signer: %w", err)
+	}
+
+	totpCipher, err := totp.NewCipher(config.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TOTP cipher: %w", err)
+	}
+
+	stepUpThresholds, err := util.ParseCurrencyAmounts(config.StepUpThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step-up thresholds: %w", err)
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:           config,
+		store:            store,
+		tokenMaker:       tokenMaker,
+		rateProvider:     rateProvider,
+		cursorSigner:     cursorSigner,
+		rateLimiter:      rateLimiter,
+		totpCipher:       totpCipher,
+		authChecker:      auth.NewChecker(config, store, totpCipher),
+		sessions:         auth.NewSessions(config, store, tokenMaker),
+		stepUpThresholds: stepUpThresholds,
+		logger:           logger,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("role", validRole)
 	}
 
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
-	router := gin.Default()
+func (server *Server) setupRouter() error {
+	router := gin.New()
+	if err := router.SetTrustedProxies(server.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	router.ContextWithFallback = true // Handlers pass the gin ctx to the store, which needs the logger of the req ctx
+	// The span comes first, so the logs of the req carry its trace ID
+	router.Use(otelgin.Middleware(tracing.ServiceName), requestLogger(server.logger, healthPaths), requestMetrics(), gin.Recovery())
+
+	router.GET("/healthz", server.getHealthz)
+	router.GET("/readyz", server.getReadyz)
+
+	docHandler, err := doc.NewHTTPHandler()
+	if err != nil {
+		return fmt.Errorf("cannot create doc handler: %w", err)
+	}
+	router.GET("/openapi.json", gin.WrapH(docHandler))
+	router.GET("/swagger/*path", gin.WrapH(docHandler))
+
+	// Creating things can be retried safely with an Idempotency-Key header
+	idempotent := idempotencyMiddleware(server.store, server.config.IdempotencyKeyTTL, server.config.IdempotencyKeyAbandonTimeout)
 
 	// Add routes to router
-	router.POST("/users", server.createUser)
-	router.POST("/users/login", server.loginUser)
+	router.POST("/users", idempotent, server.createUser)
+	router.POST("/users/login",
+		rateLimit(server.rateLimiter, "login_ip", server.loginLimit(server.config.LoginRateLimitPerIP), clientIPKey),
+		rateLimit(server.rateLimiter, "login_username", server.loginLimit(server.config.LoginRateLimitPerUsername), loginUsernameKey),
+		server.loginUser,
+	)
+	router.POST("/users/login/mfa",
+		rateLimit(server.rateLimiter, "login_ip", server.loginLimit(server.config.LoginRateLimitPerIP), clientIPKey),
+		rateLimit(server.rateLimiter, "login_username", server.loginLimit(server.config.LoginRateLimitPerUsername), server.mfaUsernameKey),
+		server.loginUserMFA,
+	)
+	router.POST("/users/forgot_password",
+		rateLimit(server.rateLimiter, "reset_ip", server.loginLimit(server.config.LoginRateLimitPerIP), clientIPKey),
+		rateLimit(server.rateLimiter, "reset_email", server.loginLimit(server.config.LoginRateLimitPerUsername), resetEmailKey),
+		server.forgotPassword,
+	)
+	router.POST("/users/reset_password",
+		rateLimit(server.rateLimiter, "reset_ip", server.loginLimit(server.config.LoginRateLimitPerIP), clientIPKey),
+		server.resetPassword,
+	)
+	router.GET("/users/verify_email", server.verifyEmail)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/.well-known/jwks.json", server.getKeySet)
 
 	// APIs below need to go thru the middleware first
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.sessions))
+
+	authRoutes.GET("/users/:username", server.getUser)
+	authRoutes.PUT("/users/me/password", server.changePassword)
+	authRoutes.POST("/users/me/verify_email",
+		rateLimit(server.rateLimiter, "verify_email", server.loginLimit(server.config.LoginRateLimitPerUsername), authUsernameKey),
+		server.resendVerifyEmail,
+	)
+	authRoutes.POST("/users/me/totp", server.enrollTOTP)
+	authRoutes.POST("/users/me/totp/confirm", server.confirmTOTP)
+	authRoutes.DELETE("/users/me/totp", server.disableTOTP)
 
-	authRoutes.POST("/accounts", server.createAccount)
+	authRoutes.POST("/accounts", idempotent, server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	authRoutes.GET("/accounts", server.listAccount)
+	authRoutes.POST("/accounts/:id/withdrawals", idempotent, server.createWithdrawal)
+	authRoutes.GET("/accounts/:id/entries", server.listEntries)
+
+	// Checked before the idempotency key, so a user can retry the same transfer once they verified their email or stepped up
+	verified := requireVerifiedEmail(server.store, server.config.TransfersRequireVerifiedEmail)
+	steppedUp := requireStepUp(server.stepUpThresholds, server.config.StepUpMaxAge)
+	authRoutes.POST("/transfers", verified, steppedUp, idempotent, server.createTransfer)
+	authRoutes.GET("/transfers", server.listTransfers)
+	authRoutes.GET("/fx/quote", server.getQuote)
+
+	authRoutes.POST("/tokens/step_up", server.stepUp)
+
+	authRoutes.GET("/sessions", server.listSessions)
+	authRoutes.DELETE("/sessions/:id", server.deleteSession)
+	authRoutes.DELETE("/sessions", server.deleteSessions)
+
+	// Deposits create money in the bank, so only staff who took it in can record them
+	depositRoutes := router.Group("/").Use(authMiddleware(server.sessions), requireRoles(auth.DepositRoles...))
+	depositRoutes.POST("/accounts/:id/deposits", idempotent, server.createDeposit)
 
-	authRoutes.POST("/transfers", server.createTransfer)
+	// APIs below are only for admins
+	adminRoutes := router.Group("/").Use(authMiddleware(server.sessions), requireRoles(util.AdminRole))
+	adminRoutes.PATCH("/users/:username/role", server.updateUserRole)
 
 	server.router = router
+	return nil
 }
 
-// Start to run the HTTP server on an address
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// NewHTTPServer serves the router on an address, with the timeouts of the config
+func (server *Server) NewHTTPServer(address string) *http.Server {
+	return &http.Server{
+		Addr:         address,
+		Handler:      server.router,
+		ReadTimeout:  server.config.HTTPReadTimeout,
+		WriteTimeout: server.config.HTTPWriteTimeout,
+		IdleTimeout:  server.config.HTTPIdleTimeout,
+	}
 }
 
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// Machine-readable codes for errors clients are expected to handle
+const (
+	codeInsufficientFunds        = "insufficient_funds"
+	codeInvalidCredentials       = "invalid_credentials"
+	codeInvalidResetToken        = "invalid_reset_token"
+	codeInvalidVerifyEmail       = "invalid_verify_email"
+	codeEmailNotVerified         = "email_not_verified"
+	codeInvalidTOTPCode          = "invalid_totp_code"
+	codeStepUpRequired           = "step_up_required"
+	codeRateLimited              = "rate_limited"
+	codeAccountInactive          = "account_inactive"
+	codeIdempotencyKeyReused     = "idempotency_key_reused"
+	codeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+)
+
+func errorResponseWithCode(code string, err error) gin.H {
+	return gin.H{"error": err.Error(), "code": code}
+}
+
+// txErrorResponse responds to an error of a tx which moves money
+func txErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrInsufficientFunds):
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponseWithCode(codeInsufficientFunds, err))
+	case errors.Is(err, db.ErrAccountInactive):
+		ctx.JSON(http.StatusForbidden, errorResponseWithCode(codeAccountInactive, err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	"github.com/kvnyijia/bank-app/doc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPServerShutdownDrainsRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.config.HTTPReadTimeout = time.Second
+
+	// Stands in for a long req like a transfer
+	started := make(chan struct{})
+	server.router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.Status(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	httpServer := server.NewHTTPServer(listener.Addr().String())
+	require.Equal(t, time.Second, httpServer.ReadTimeout)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.Serve(listener) }()
+
+	rspCode := make(chan int, 1)
+	go func() {
+		rsp, err := http.Get(fmt.Sprintf("http://%s/slow", listener.Addr()))
+		if err != nil {
+			rspCode <- 0
+			return
+		}
+		rsp.Body.Close()
+		rspCode <- rsp.StatusCode
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, httpServer.Shutdown(ctx))
+
+	// The in-flight req still completes, but no new connection is accepted
+	require.Equal(t, http.StatusOK, <-rspCode)
+	require.ErrorIs(t, <-serveErr, http.ErrServerClosed)
+
+	_, err = http.Get(fmt.Sprintf("http://%s/slow", listener.Addr()))
+	require.Error(t, err)
+}
+
+// TestHTTPSpecCoversRoutes keeps doc/http_openapi.yaml in step with the router
+func TestHTTPSpecCoversRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	data, err := doc.HTTPSpec()
+	require.NoError(t, err)
+
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(data, &spec))
+
+	documented := map[string]bool{}
+	pathParam := regexp.MustCompile(`:(\w+)`)
+	for _, route := range server.router.Routes() {
+		if route.Path == "/openapi.json" || strings.HasPrefix(route.Path, "/swagger/") {
+			continue
+		}
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		require.Contains(t, spec.Paths[path], method, "%s %s is not documented", route.Method, route.Path)
+		documented[method+" "+path] = true
+	}
+
+	// Nor does the spec document routes which don't exist
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			require.True(t, documented[method+" "+path], "%s %s does not exist", method, path)
+		}
+	}
+}
+
+func TestHTTPDocs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	for _, path := range []string{"/openapi.json", "/swagger/", "/swagger/swagger-initializer.js"} {
+		recorder := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, path)
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/auth"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/token"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		metrics.ObserveTokenVerificationFailure(err)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if refreshPayload.Purpose != token.PurposeRefresh {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errNotRefreshToken))
+		return
+	}
+
+	authSession, err := server.store.GetAuthSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	session := authSession.Session
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		err := errors.New("incorrect session user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		err := errors.New("expired session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if refreshPayload.IssuedAt.Before(authSession.PasswordChangedAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errTokenBeforePasswordChange))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username:  refreshPayload.Username,
+		Role:      refreshPayload.Role,
+		SessionID: session.ID,
+		Duration:  server.config.AccessTokenDuration,
+		AuthTime:  refreshPayload.AuthTime,
+		AMR:       refreshPayload.AMR,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type stepUpRequest struct {
+	Password string `json:"password"`
+	// Users with TOTP on step up with a TOTP code or a recovery code instead of their password
+	Code string `json:"code"`
+}
+
+type stepUpResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// stepUp re-authenticates the logged in user, and gives them an access token of the same session which counts as a
+// recent authentication. The refresh token stays as it is, so renewed access tokens need another step-up.
+func (server *Server) stepUp(ctx *gin.Context) {
+	var req stepUpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// A stolen token must not be enough to guess the password or codes, so wrong ones count like failed logins
+	amr, err := server.authChecker.StepUp(ctx, user, req.Password, req.Code)
+	if err != nil {
+		if errors.Is(err, auth.ErrStepUpMethod) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		credentialsErrorResponse(ctx, err)
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username:  authPayload.Username,
+		Role:      authPayload.Role,
+		SessionID: authPayload.SessionID,
+		Duration:  server.config.AccessTokenDuration,
+		AuthTime:  time.Now(),
+		AMR:       amr,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := stepUpResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// Publishes the public keys, so other services can verify our tokens without sharing a secret
+func (server *Server) getKeySet(ctx *gin.Context) {
+	keySet := token.JSONWebKeySet{Keys: []token.JSONWebKey{}}
+	if provider, ok := server.tokenMaker.(token.KeySetProvider); ok {
+		keySet = provider.KeySet()
+	}
+	ctx.JSON(http.StatusOK, keySet)
+}
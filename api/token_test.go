@@ -0,0 +1,403 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupToken    func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload)
+		buildStubs    func(store *mockdb.MockStore, payload *token.Payload)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.GetAuthSessionRow{Session: randomSession(payload)}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				accessToken, payload, err := tokenMaker.CreateToken(token.CreateTokenParams{
+					Username: user.Username,
+					Duration: time.Hour,
+				})
+				require.NoError(t, err)
+				return accessToken, payload
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.GetAuthSessionRow{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				session := randomSession(payload)
+				session.IsBlocked = true
+
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.GetAuthSessionRow{Session: session}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				session := randomSession(payload)
+				session.Username = "someone_else"
+
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.GetAuthSessionRow{Session: session}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChanged",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.GetAuthSessionRow{
+						Session:           randomSession(payload),
+						PasswordChangedAt: payload.IssuedAt.Add(time.Second),
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return createRefreshToken(t, tokenMaker, user.Username, time.Hour)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, payload := tc.setupToken(t, server.tokenMaker)
+			tc.buildStubs(store, payload)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			url := "/tokens/renew_access"
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func createRefreshToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) (string, *token.Payload) {
+	refreshToken, payload, err := tokenMaker.CreateToken(token.CreateTokenParams{
+		Username: username,
+		Duration: duration,
+		Purpose:  token.PurposeRefresh,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, refreshToken)
+	return refreshToken, payload
+}
+
+func randomSession(payload *token.Payload) db.Session {
+	return db.Session{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	}
+}
+
+func TestGetKeySetAPI(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+
+	config := util.Config{
+		TokenSigningKey:      fmt.Sprintf("key-1:%s", base64.StdEncoding.EncodeToString(seed)),
+		CursorSigningKey:     util.RandomString(32),
+		TOTPEncryptionKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+	server, err := NewServer(config, nil, ratelimit.NewMemoryStore(), zerolog.Nop())
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var keySet token.JSONWebKeySet
+	err = json.Unmarshal(recorder.Body.Bytes(), &keySet)
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 1)
+	require.Equal(t, "key-1", keySet.Keys[0].KeyID)
+	require.Equal(t, "EdDSA", keySet.Keys[0].Algorithm)
+}
+
+func TestStepUpAPI(t *testing.T) {
+	user, password := randomUser(t)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	totpUser := user
+	totpUser.TotpEnabled = true
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, authPayload *token.Payload)
+	}{
+		{
+			name: "Password",
+			user: user,
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, authPayload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp stepUpResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+
+				// The new token belongs to the same session, and counts as a recent authentication
+				payload, err := server.tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, authPayload.SessionID, payload.SessionID)
+				require.WithinDuration(t, time.Now(), payload.AuthTime, time.Second)
+				require.Equal(t, []string{token.AMRPassword}, payload.AMR)
+			},
+		},
+		{
+			name: "TOTPCode",
+			user: totpUser,
+			body: gin.H{"code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, authPayload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp stepUpResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, []string{token.AMROTP}, payload.AMR)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			user: user,
+			body: gin.H{"password": "incorrect"},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, authPayload *token.Payload) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "PasswordOfTOTPUser",
+			user: totpUser,
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, authPayload *token.Payload) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "LockedUser",
+			user: lockedUser,
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, authPayload *token.Payload) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user := tc.user
+			user.TotpSecret, err = server.totpCipher.Seal(secret)
+			require.NoError(t, err)
+
+			buildSessionStub(store, user.Username)
+			tc.buildStubs(store, user)
+
+			accessToken, authPayload, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+				Username: user.Username,
+				Role:     user.Role,
+				Duration: time.Minute,
+			})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/tokens/step_up", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			req.Header.Set(authorizationHeaderKey, auth.AuthorizationTypeBearer+" "+accessToken)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder, server, authPayload)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+)
+
+var (
+	errTOTPEnabled      = errors.New("TOTP is already enabled")
+	errTOTPNotEnabled   = errors.New("TOTP is not enabled")
+	errInvalidTOTPCode  = errors.New("TOTP code is invalid")
+	errNotMFAChallenge  = errors.New("token is not an MFA challenge")
+	errMFAChallengeUsed = errors.New("MFA challenge was used already")
+)
+
+type enrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	// Authenticator apps read the URI from a QR code
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// enrollTOTP gives the logged in user a new TOTP secret. TOTP is only enabled once a code of the secret is confirmed,
+// so enrolling again before that starts over with another secret.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	sealedSecret, err := server.totpCipher.Seal(secret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.SetTOTPSecret(ctx, db.SetTOTPSecretParams{
+		Username:   authPayload.Username,
+		TotpSecret: sealedSecret,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errTOTPEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := enrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(server.config.TOTPIssuer, user.Username, secret),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type confirmTOTPRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Code            string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	// Each code logs in once instead of a TOTP code. They're only ever shown here.
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables TOTP for the logged in user, who has to know their password & proves their authenticator app
+// has the enrolled secret. Otherwise a stolen token would be enough to lock the user out with a secret of the thief.
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// A stolen token must not be enough to guess the password, so wrong ones count like failed logins
+	if err := server.authChecker.CheckPassword(ctx, user, req.CurrentPassword); err != nil {
+		credentialsErrorResponse(ctx, err)
+		return
+	}
+
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errTOTPEnabled))
+		return
+	}
+	if user.TotpSecret == "" {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrTOTPNotEnrolled))
+		return
+	}
+
+	secret, err := server.totpCipher.Open(user.TotpSecret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponseWithCode(codeInvalidTOTPCode, errInvalidTOTPCode))
+		return
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	codeHashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		codeHashes[i] = util.HashSecureToken(totp.NormalizeRecoveryCode(code))
+	}
+
+	_, err = server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               step,
+		RecoveryCodeHashes: codeHashes,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrTOTPNotEnrolled) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: recoveryCodes})
+}
+
+type disableTOTPRequest struct {
+	// A TOTP code or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+// disableTOTP turns TOTP off for the logged in user, who has to know a current code
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !user.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errTOTPNotEnabled))
+		return
+	}
+
+	// A stolen token must not be enough to guess codes, so wrong ones count like failed logins
+	if err := server.authChecker.CheckCode(ctx, user, req.Code); err != nil {
+		credentialsErrorResponse(ctx, err)
+		return
+	}
+
+	user, err = server.store.DisableTOTPTx(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type mfaChallengeResponse struct {
+	MFARequired bool `json:"mfa_required"`
+	// Exchanged for the tokens of a login at /users/login/mfa, along with a code
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+// mfaChallenge answers a login with the right password of a user with TOTP on
+func (server *Server) mfaChallenge(ctx *gin.Context, user db.User) {
+	mfaToken, mfaPayload, err := server.sessions.CreateMFAChallenge(user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := mfaChallengeResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: mfaPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type loginUserMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// A TOTP code or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+// loginUserMFA finishes the login of a user with TOTP on, once the second factor checks out
+func (server *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginUserMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	mfaPayload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if mfaPayload.Purpose != token.PurposeMFAChallenge {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errNotMFAChallenge))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, mfaPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponseWithCode(codeInvalidCredentials, errInvalidCredentials))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The password the challenge was issued for must still be the current one
+	if mfaPayload.IssuedAt.Before(user.PasswordChangedAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errTokenBeforePasswordChange))
+		return
+	}
+
+	if err := server.authChecker.CheckCode(ctx, user, req.Code); err != nil {
+		credentialsErrorResponse(ctx, err)
+		return
+	}
+
+	// Each challenge is exchanged for one login at most
+	_, err = server.store.UseMFAChallenge(ctx, db.UseMFAChallengeParams{
+		Username: user.Username,
+		IssuedAt: mfaPayload.IssuedAt,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errMFAChallengeUsed))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.createLogin(ctx, user, []string{token.AMRPassword, token.AMROTP})
+}
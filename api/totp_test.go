@@ -0,0 +1,822 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+// wrongTOTPCode is a code which is valid for none of the steps around now
+func wrongTOTPCode(t *testing.T, secret string) string {
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok := totp.Validate(secret, code, time.Now()); !ok {
+			return code
+		}
+	}
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, sealedSecret *string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, sealedSecret string)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, sealedSecret *string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					SetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.SetTOTPSecretParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						*sealedSecret = arg.TotpSecret
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, sealedSecret string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+
+				// Only the sealed secret is stored
+				require.NotEqual(t, rsp.Secret, sealedSecret)
+				secret, err := server.totpCipher.Open(sealedSecret)
+				require.NoError(t, err)
+				require.Equal(t, rsp.Secret, secret)
+
+				uri, err := url.Parse(rsp.ProvisioningURI)
+				require.NoError(t, err)
+				require.Equal(t, "otpauth", uri.Scheme)
+				require.Equal(t, rsp.Secret, uri.Query().Get("secret"))
+				require.Equal(t, "bank-app", uri.Query().Get("issuer"))
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, sealedSecret *string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					SetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, sealedSecret string) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuth(t, req, tokenMaker, auth.AuthorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, sealedSecret *string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					SetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, sealedSecret string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore, sealedSecret *string) {
+				store.EXPECT().
+					SetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, sealedSecret string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			var sealedSecret string
+			tc.buildStubs(store, &sealedSecret)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, "/users/me/totp", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder, server, sealedSecret)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, password := randomUser(t)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	enabledUser := user
+	enabledUser.TotpEnabled = true
+
+	testCases := []struct {
+		name string
+		// The secret of the user is sealed with the key of the test server
+		user          db.User
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string)
+		checkResponse func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string)
+	}{
+		{
+			name: "OK",
+			user: user,
+			body: gin.H{"current_password": password, "code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnableTOTPTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, totp.Step(time.Now()), arg.Step, 1)
+						*recoveryCodeHashes = arg.RecoveryCodeHashes
+						return user, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.RecoveryCodes, totp.RecoveryCodeCount)
+
+				// Only the hashes of the recovery codes are stored
+				require.Len(t, recoveryCodeHashes, totp.RecoveryCodeCount)
+				for i, code := range rsp.RecoveryCodes {
+					require.Equal(t, util.HashSecureToken(totp.NormalizeRecoveryCode(code)), recoveryCodeHashes[i])
+				}
+			},
+		},
+		{
+			name: "WrongCode",
+			user: user,
+			body: gin.H{"current_password": password, "code": wrongTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var rsp gin.H
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, codeInvalidTOTPCode, rsp["code"])
+			},
+		},
+		{
+			name: "WrongPassword",
+			user: user,
+			body: gin.H{"current_password": "wrong-password", "code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "NoPassword",
+			user: user,
+			body: gin.H{"code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			user: enabledUser,
+			body: gin.H{"current_password": password, "code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			user: user,
+			body: gin.H{"current_password": password, "code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				user.TotpSecret = ""
+
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			user: user,
+			body: gin.H{"current_password": password, "code": "abcdef"},
+			buildStubs: func(store *mockdb.MockStore, user db.User, recoveryCodeHashes *[]string) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, recoveryCodeHashes []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user := tc.user
+			user.TotpSecret, err = server.totpCipher.Seal(secret)
+			require.NoError(t, err)
+
+			var recoveryCodeHashes []string
+			tc.buildStubs(store, user, &recoveryCodeHashes)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/me/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuth(t, req, server.tokenMaker, auth.AuthorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(recorder, recoveryCodeHashes)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.TotpEnabled = true
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	disabledUser := user
+	disabledUser.TotpEnabled = false
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user,
+			body: gin.H{"code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseTOTPStepParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, totp.Step(time.Now()), arg.Step, 1)
+						return user, nil
+					})
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabledUser, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, disabledUser)
+			},
+		},
+		{
+			name: "WrongCode",
+			user: user,
+			body: gin.H{"code": wrongTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "LockedUser",
+			user: lockedUser,
+			body: gin.H{"code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "NotEnabled",
+			user: disabledUser,
+			body: gin.H{"code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user := tc.user
+			user.TotpSecret, err = server.totpCipher.Seal(secret)
+			require.NoError(t, err)
+			tc.buildStubs(store, user)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodDelete, "/users/me/totp", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuth(t, req, server.tokenMaker, auth.AuthorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginUserMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.TotpEnabled = true
+	user.FailedLoginAttempts = 1
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	recoveryCode := "abcde-fghij"
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	passwordChangedUser := user
+	passwordChangedUser.PasswordChangedAt = time.Now().Add(time.Second)
+
+	mfaToken := func(t *testing.T, tokenMaker token.Maker) string {
+		mfaToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+			Username: user.Username,
+			Role:     user.Role,
+			Duration: time.Minute,
+			Purpose:  token.PurposeMFAChallenge,
+		})
+		require.NoError(t, err)
+		return mfaToken
+	}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		mfaToken      func(t *testing.T, tokenMaker token.Maker) string
+		code          string
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			user:     user,
+			mfaToken: mfaToken,
+			code:     currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseTOTPStepParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, totp.Step(time.Now()), arg.Step, 1)
+						return user, nil
+					})
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseMFAChallengeParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now(), arg.IssuedAt, time.Second)
+						return user, nil
+					})
+				store.EXPECT().
+					ResetFailedLogins(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+			},
+		},
+		{
+			name:     "RecoveryCode",
+			user:     user,
+			mfaToken: mfaToken,
+			code:     " ABCDE-FGHIJ ",
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username: user.Username,
+						CodeHash: util.HashSecureToken(totp.NormalizeRecoveryCode(recoveryCode)),
+					})).
+					Times(1).
+					Return(db.RecoveryCode{Username: user.Username}, nil)
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ResetFailedLogins(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "UsedChallenge",
+			user:     user,
+			mfaToken: mfaToken,
+			code:     currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "WrongCode",
+			user:     user,
+			mfaToken: mfaToken,
+			code:     wrongTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name:     "ReplayedCode",
+			user:     user,
+			mfaToken: mfaToken,
+			code:     currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name:     "UsedRecoveryCode",
+			user:     user,
+			mfaToken: mfaToken,
+			code:     recoveryCode,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name:     "LockedUser",
+			user:     lockedUser,
+			mfaToken: mfaToken,
+			code:     currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name:     "PasswordChanged",
+			user:     passwordChangedUser,
+			mfaToken: mfaToken,
+			code:     currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			user: user,
+			mfaToken: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+					Username: user.Username,
+					Role:     user.Role,
+					Duration: time.Minute,
+				})
+				require.NoError(t, err)
+				return accessToken
+			},
+			code: currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			user: user,
+			mfaToken: func(t *testing.T, tokenMaker token.Maker) string {
+				mfaToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+					Username: user.Username,
+					Role:     user.Role,
+					Duration: -time.Minute,
+					Purpose:  token.PurposeMFAChallenge,
+				})
+				require.NoError(t, err)
+				return mfaToken
+			},
+			code: currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "NoCode",
+			user:     user,
+			mfaToken: mfaToken,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user := tc.user
+			user.TotpSecret, err = server.totpCipher.Seal(secret)
+			require.NoError(t, err)
+			tc.buildStubs(store, user)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"mfa_token": tc.mfaToken(t, server.tokenMaker),
+				"code":      tc.code,
+			})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(recorder)
+		})
+	}
+}
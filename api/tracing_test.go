@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/tracing"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestTracing(t *testing.T) {
+	provider, exporter := tracing.NewInMemoryProvider()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	// The store gets the span of the req, so its queries end up in the same trace
+	var storeSpan trace.SpanContext
+	store := mockdb.NewMockStore(ctrl)
+	buildSessionStub(store, user.Username)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+			storeSpan = trace.SpanContextFromContext(ctx)
+			return account, nil
+		})
+
+	logs := &bytes.Buffer{}
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		CursorSigningKey:    util.RandomString(32),
+		TOTPEncryptionKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		FXRatesFile:         testRatesFile,
+	}
+	server, err := NewServer(config, store, ratelimit.NewMemoryStore(), zerolog.New(logs))
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	addAuth(t, request, server.tokenMaker, auth.AuthorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "/accounts/:id", spans[0].Name)
+
+	traceID := spans[0].SpanContext.TraceID()
+	require.True(t, storeSpan.IsValid())
+	require.Equal(t, traceID, storeSpan.TraceID())
+	require.Equal(t, traceID.String(), lastLogEntry(t, logs)[logging.TraceIDField])
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kvnyijia/bank-app/auth"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
 	"github.com/kvnyijia/bank-app/token"
 )
@@ -31,33 +33,50 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if fromAccount.Owner != authPayload.Username {
+	if !auth.CanWrite(authPayload, fromAccount.Owner) {
 		err := errors.New("from account does not belong to the authenticated user")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	toAccount, valid := server.findAccount(ctx, req.ToAccountID)
 	if !valid {
 		return
 	}
 
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
-	}
+	var result db.TransferTxResult
+	var err error
+	if toAccount.Currency == req.Currency {
+		result, err = server.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount,
+		})
+	} else {
+		// The to account is credited in its own currency at the current rate
+		rate, toAmount, valid := server.convert(ctx, req.Currency, toAccount.Currency, req.Amount)
+		if !valid {
+			return
+		}
 
-	result, err := server.store.TransferTx(ctx, arg)
+		result, err = server.store.ExchangeTransferTx(ctx, db.ExchangeTransferTxParams{
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  rate.Value,
+		})
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		txErrorResponse(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, result)
 }
 
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+// findAccount gets the account, or writes the error response
+func (server *Server) findAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -68,6 +87,14 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return account, false
 	}
+	return account, true
+}
+
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, valid := server.findAccount(ctx, accountID)
+	if !valid {
+		return account, false
+	}
 
 	if account.Currency != currency {
 		err := fmt.Errorf("account [%d] currency mismatch: %s cs %s", account.ID, account.Currency, currency)
@@ -76,3 +103,69 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 	}
 	return account, true
 }
+
+type listTransfersRequest struct {
+	historyFilter
+	pageRequest
+	AccountID             int64 `form:"account_id" binding:"required,min=1"`
+	CounterpartyAccountID int64 `form:"counterparty_account_id" binding:"omitempty,min=1"`
+}
+
+// listTransfers shows the transfers from & to an account, newest first
+func (server *Server) listTransfers(ctx *gin.Context) {
+	var req listTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.findAccount(ctx, req.AccountID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !auth.CanRead(authPayload, account.Owner) {
+		err := errors.New("account does't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	scope := fmt.Sprintf("transfers:%d", account.ID)
+	before, valid := server.pageCursor(ctx, req.pageRequest, scope)
+	if !valid {
+		return
+	}
+
+	arg := db.ListAccountTransfersParams{
+		AccountID: account.ID,
+		CounterpartyAccountID: sql.NullInt64{
+			Int64: req.CounterpartyAccountID,
+			Valid: req.CounterpartyAccountID > 0,
+		},
+		StartTime:       req.startTime(),
+		EndTime:         req.endTime(),
+		Direction:       req.direction(),
+		MinAmount:       req.minAmount(),
+		MaxAmount:       req.maxAmount(),
+		BeforeCreatedAt: before.NullCreatedAt(),
+		BeforeID:        before.NullID(),
+		Limit:           req.limit() + 1,
+	}
+
+	transfers, err := server.store.ListAccountTransfers(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := newListResponse(server.cursorSigner, transfers, req.limit(), scope, func(transfer db.Transfer) (time.Time, int64) {
+		return transfer.CreatedAt, transfer.ID
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
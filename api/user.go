@@ -2,11 +2,16 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kvnyijia/bank-app/auth"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/token"
 	"github.com/kvnyijia/bank-app/util"
 	pq "github.com/lib/pq"
 )
@@ -22,6 +27,9 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	TOTPEnabled       bool      `json:"totp_enabled"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -31,6 +39,9 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		TOTPEnabled:       user.TotpEnabled,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -57,7 +68,8 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	// The email to verify the address is sent by the email worker
+	result, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -70,18 +82,83 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	res := newUserResponse(user)
+	res := newUserResponse(result.User)
 	ctx.JSON(http.StatusOK, res)
 }
 
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"id" binding:"required,min=1"`
+	SecretCode string `form:"code" binding:"required"`
+}
+
+// verifyEmail is where the link in the verification email of a new user leads
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:        req.EmailID,
+		SecretCodeHash: util.HashSecureToken(req.SecretCode),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidVerifyEmail) {
+			ctx.JSON(http.StatusBadRequest, errorResponseWithCode(codeInvalidVerifyEmail, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+var errEmailVerified = errors.New("email is already verified")
+
+type resendVerifyEmailResponse struct {
+	Message string `json:"message"`
+}
+
+// resendVerifyEmail queues another verification email for the logged in user, e.g. if the link of the last one expired
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsEmailVerified {
+		ctx.JSON(http.StatusConflict, errorResponse(errEmailVerified))
+		return
+	}
+
+	_, err = server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resendVerifyEmailResponse{Message: "a link to verify the email will be sent to " + user.Email})
+}
+
 type loginUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
@@ -91,34 +168,139 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	// Unknown users, wrong passwords & locked users all get the same answer, after as long,
+	// so it doesn't tell which usernames exist
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			util.CheckPasswordOfMissingUser(req.Password)
+			ctx.JSON(http.StatusUnauthorized, errorResponseWithCode(codeInvalidCredentials, errInvalidCredentials))
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if err := server.authChecker.CheckPassword(ctx, user, req.Password); err != nil {
+		credentialsErrorResponse(ctx, err)
+		return
+	}
+
+	if user.TotpEnabled {
+		server.mfaChallenge(ctx, user)
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(
-		user.Username,
-		server.config.AccessTokenDuration,
-	)
+	server.createLogin(ctx, user, []string{token.AMRPassword})
+}
+
+// createLogin starts a session for the user, who proved who they are with the methods, and responds with its tokens
+func (server *Server) createLogin(ctx *gin.Context, user db.User, amr []string) {
+	login, err := server.sessions.CreateLogin(ctx, auth.CreateLoginParams{
+		User:      user,
+		AMR:       amr,
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+		SessionID:             login.Session.ID,
+		AccessToken:           login.AccessToken,
+		AccessTokenExpiresAt:  login.AccessPayload.ExpiredAt,
+		RefreshToken:          login.RefreshToken,
+		RefreshTokenExpiresAt: login.RefreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+var errInvalidCredentials = auth.ErrInvalidCredentials
+
+// credentialsErrorResponse answers a req whose credentials didn't check out, or couldn't be checked
+func credentialsErrorResponse(ctx *gin.Context, err error) {
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		ctx.JSON(http.StatusUnauthorized, errorResponseWithCode(codeInvalidCredentials, err))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+}
+
+// loginLimit lets the number of logins thru per configured interval
+func (server *Server) loginLimit(logins int) ratelimit.Limit {
+	return ratelimit.Limit{Burst: logins, Interval: server.config.LoginRateLimitInterval}
+}
+
+type getUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+func (server *Server) getUser(ctx *gin.Context) {
+	var req getUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Username != req.Username && !auth.HasRole(authPayload, util.AdminRole) {
+		err := errors.New("cannot view other users")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri getUserRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The role is baked into the tokens, so the user has to log in again to pick up the new one
+	_, err = server.store.BlockUserSessions(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
@@ -0,0 +1,173 @@
+// Package auth checks the passwords & codes users prove who they are with, locks out users who get them wrong
+// too often, starts & checks their sessions, and decides what each role may do, so the HTTP & gRPC APIs can't
+// drift apart on any of it
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrInvalidCredentials is returned for wrong passwords & codes, and for locked out users whatever they sent,
+	// so the answer doesn't tell which
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrStepUpMethod       = errors.New("users with TOTP on step up with a code, others with their password")
+)
+
+// Checker checks credentials against the store
+type Checker struct {
+	config     util.Config
+	store      db.Store
+	totpCipher *totp.Cipher
+}
+
+func NewChecker(config util.Config, store db.Store, totpCipher *totp.Cipher) *Checker {
+	return &Checker{
+		config:     config,
+		store:      store,
+		totpCipher: totpCipher,
+	}
+}
+
+// CheckPassword returns ErrInvalidCredentials if the password is wrong, which counts as a failed login,
+// or if the user is locked out
+func (checker *Checker) CheckPassword(ctx context.Context, user db.User, password string) error {
+	// The password is checked even if the user is locked out, so the answer takes as long either way
+	err := util.CheckPassword(password, user.HashedPassword)
+	if time.Now().Before(user.LockedUntil) {
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		return checker.fail(ctx, user.Username)
+	}
+	return nil
+}
+
+// CheckCode returns ErrInvalidCredentials unless the code is a TOTP code or a recovery code of the user,
+// which has TOTP on & isn't locked out. Wrong codes count as failed logins.
+func (checker *Checker) CheckCode(ctx context.Context, user db.User, code string) error {
+	if time.Now().Before(user.LockedUntil) || !user.TotpEnabled {
+		return ErrInvalidCredentials
+	}
+
+	ok, err := checker.useCode(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return checker.fail(ctx, user.Username)
+	}
+	return nil
+}
+
+// StepUp re-authenticates the user with a code if they have TOTP on, or with their password if not,
+// and returns the methods they used. The failed logins of the user are reset once it checks out.
+func (checker *Checker) StepUp(ctx context.Context, user db.User, password string, code string) ([]string, error) {
+	if (user.TotpEnabled && code == "") || (!user.TotpEnabled && password == "") {
+		return nil, ErrStepUpMethod
+	}
+
+	var err error
+	var amr []string
+	if user.TotpEnabled {
+		err = checker.CheckCode(ctx, user, code)
+		amr = []string{token.AMROTP}
+	} else {
+		err = checker.CheckPassword(ctx, user, password)
+		amr = []string{token.AMRPassword}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.FailedLoginAttempts > 0 {
+		if err := checker.store.ResetFailedLogins(ctx, user.Username); err != nil {
+			return nil, err
+		}
+	}
+	return amr, nil
+}
+
+// useCode reports whether the code is a TOTP code of the user, or one of their recovery codes.
+// Either is used up by the check, so no code works twice.
+func (checker *Checker) useCode(ctx context.Context, user db.User, code string) (bool, error) {
+	if len(code) == totp.Digits {
+		secret, err := checker.totpCipher.Open(user.TotpSecret)
+		if err != nil {
+			return false, err
+		}
+
+		step, ok := totp.Validate(secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		_, err = checker.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Username: user.Username,
+			Step:     step,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	_, err := checker.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.HashSecureToken(totp.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// fail records the failed login & returns ErrInvalidCredentials, or the error recording it
+func (checker *Checker) fail(ctx context.Context, username string) error {
+	if err := checker.recordFailedLogin(ctx, username); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// recordFailedLogin counts the failed login of the user, who is locked out once there are too many in a row
+func (checker *Checker) recordFailedLogin(ctx context.Context, username string) error {
+	if checker.config.LoginMaxAttempts <= 0 {
+		return nil
+	}
+
+	user, err := checker.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+		Username:          username,
+		MaxAttempts:       int32(checker.config.LoginMaxAttempts),
+		LockoutSeconds:    checker.config.LoginLockout.Seconds(),
+		MaxLockoutSeconds: checker.config.LoginMaxLockout.Seconds(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if time.Now().Before(user.LockedUntil) {
+		zerolog.Ctx(ctx).Warn().
+			Str(logging.UsernameField, user.Username).
+			Int32("failed_login_attempts", user.FailedLoginAttempts).
+			Time("locked_until", user.LockedUntil).
+			Msg("user locked out")
+	}
+	return nil
+}
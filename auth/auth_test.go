@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChecker(t *testing.T, store db.Store) (*Checker, *totp.Cipher) {
+	config := util.Config{
+		LoginMaxAttempts: 3,
+		LoginLockout:     time.Minute,
+		LoginMaxLockout:  time.Hour,
+	}
+	cipher, err := totp.NewCipher(util.RandomString(32))
+	require.NoError(t, err)
+	return NewChecker(config, store, cipher), cipher
+}
+
+func randomUser(t *testing.T) (db.User, string) {
+	password := util.RandomString(6)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+
+	user := db.User{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		Email:          util.RandomEmail(),
+	}
+	return user, password
+}
+
+// withTOTP turns TOTP on for the user & returns a current code
+func withTOTP(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	user.TotpSecret, err = cipher.Seal(secret)
+	require.NoError(t, err)
+	user.TotpEnabled = true
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestCheckPassword(t *testing.T) {
+	user, password := randomUser(t)
+	locked := user
+	locked.LockedUntil = time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name        string
+		user        db.User
+		password    string
+		buildStubs  func(store *mockdb.MockStore)
+		expectedErr error
+	}{
+		{
+			name:     "OK",
+			user:     user,
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:     "WrongPassword",
+			user:     user,
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Eq(db.RecordFailedLoginParams{
+						Username:          user.Username,
+						MaxAttempts:       3,
+						LockoutSeconds:    60,
+						MaxLockoutSeconds: 3600,
+					})).
+					Times(1).
+					Return(user, nil)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			// Even the right password doesn't get a locked out user in, and guesses aren't counted
+			name:     "Locked",
+			user:     locked,
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "RecordFailedLoginError",
+			user:     user,
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			expectedErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			checker, _ := newTestChecker(t, store)
+			err := checker.CheckPassword(context.Background(), tc.user, tc.password)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestCheckCode(t *testing.T) {
+	testCases := []struct {
+		name        string
+		setupUser   func(t *testing.T, cipher *totp.Cipher, user *db.User) string
+		buildStubs  func(store *mockdb.MockStore, user db.User)
+		expectedErr error
+	}{
+		{
+			name: "TOTPCode",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				return withTOTP(t, cipher, user)
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseTOTPStepParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, totp.Step(time.Now()), arg.Step, 1)
+						return user, nil
+					})
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			// The step of the code was used already
+			name: "ReplayedTOTPCode",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				return withTOTP(t, cipher, user)
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name: "WrongTOTPCode",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				code := withTOTP(t, cipher, user)
+				if code == "000000" {
+					return "111111"
+				}
+				return "000000"
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name: "RecoveryCode",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				withTOTP(t, cipher, user)
+				return "ABCDE-FGHIJ"
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username: user.Username,
+						CodeHash: util.HashSecureToken("abcdefghij"),
+					})).
+					Times(1).
+					Return(db.RecoveryCode{}, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "UnknownRecoveryCode",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				withTOTP(t, cipher, user)
+				return "abcde-fghij"
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name: "Locked",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				user.LockedUntil = time.Now().Add(time.Minute)
+				return withTOTP(t, cipher, user)
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name: "TOTPNotEnabled",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				return "123456"
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name: "InternalError",
+			setupUser: func(t *testing.T, cipher *totp.Cipher, user *db.User) string {
+				return withTOTP(t, cipher, user)
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			checker, cipher := newTestChecker(t, store)
+
+			user, _ := randomUser(t)
+			code := tc.setupUser(t, cipher, &user)
+			tc.buildStubs(store, user)
+
+			err := checker.CheckCode(context.Background(), user, code)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestStepUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	checker, cipher := newTestChecker(t, store)
+
+	// Users without TOTP step up with their password, which resets their failed logins
+	user, password := randomUser(t)
+	user.FailedLoginAttempts = 2
+	store.EXPECT().ResetFailedLogins(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(nil)
+
+	amr, err := checker.StepUp(context.Background(), user, password, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{token.AMRPassword}, amr)
+
+	_, err = checker.StepUp(context.Background(), user, "", "123456")
+	require.ErrorIs(t, err, ErrStepUpMethod)
+
+	// Users with TOTP step up with a code, and their password isn't enough
+	totpUser, totpPassword := randomUser(t)
+	code := withTOTP(t, cipher, &totpUser)
+	store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(totpUser, nil)
+
+	amr, err = checker.StepUp(context.Background(), totpUser, "", code)
+	require.NoError(t, err)
+	require.Equal(t, []string{token.AMROTP}, amr)
+
+	_, err = checker.StepUp(context.Background(), totpUser, totpPassword, "")
+	require.ErrorIs(t, err, ErrStepUpMethod)
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+)
+
+// ReadAnyRoles may read resources owned by other users
+var ReadAnyRoles = []string{util.BankerRole, util.AdminRole}
+
+// DepositRoles may credit accounts with money from outside the bank, e.g. cash handed in at a branch
+var DepositRoles = []string{util.BankerRole, util.AdminRole}
+
+// HasRole reports whether the user has one of the roles
+func HasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRead reports whether the user may view a resource of the owner
+func CanRead(payload *token.Payload, owner string) bool {
+	return payload.Username == owner || HasRole(payload, ReadAnyRoles...)
+}
+
+// CanWrite reports whether the user may change a resource of the owner, e.g. move money out of an account.
+// Nobody acts on behalf of someone else, whatever their role is.
+func CanWrite(payload *token.Payload, owner string) bool {
+	return payload.Username == owner
+}
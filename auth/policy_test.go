@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	owner := util.RandomOwner()
+
+	testCases := []struct {
+		name     string
+		payload  *token.Payload
+		canRead  bool
+		canWrite bool
+	}{
+		{
+			name:     "Owner",
+			payload:  &token.Payload{Username: owner, Role: util.DepositorRole},
+			canRead:  true,
+			canWrite: true,
+		},
+		{
+			name:    "OtherDepositor",
+			payload: &token.Payload{Username: util.RandomOwner(), Role: util.DepositorRole},
+		},
+		{
+			name:    "Banker",
+			payload: &token.Payload{Username: util.RandomOwner(), Role: util.BankerRole},
+			canRead: true,
+		},
+		{
+			name:    "Admin",
+			payload: &token.Payload{Username: util.RandomOwner(), Role: util.AdminRole},
+			canRead: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.canRead, CanRead(tc.payload, owner))
+			require.Equal(t, tc.canWrite, CanWrite(tc.payload, owner))
+		})
+	}
+}
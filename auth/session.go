@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+)
+
+// AuthorizationTypeBearer is the type of the authorization header with an access token
+const AuthorizationTypeBearer = "bearer"
+
+var (
+	// ErrUnauthenticated is wrapped by every reason a token doesn't let its user in, so callers can tell them
+	// from failures to check the token
+	ErrUnauthenticated           = errors.New("unauthenticated")
+	ErrTokenBeforePasswordChange = errors.New("token was issued before the password was changed")
+	ErrRestrictedToken           = errors.New("token cannot be used to access resources")
+)
+
+type unauthenticatedError struct {
+	error
+}
+
+func (err unauthenticatedError) Unwrap() []error {
+	return []error{err.error, ErrUnauthenticated}
+}
+
+// unauthenticated wraps the reason with ErrUnauthenticated, keeping its message
+func unauthenticated(reason error) error {
+	return unauthenticatedError{reason}
+}
+
+// Sessions starts the sessions users log in to, and checks the access tokens of their requests against them
+type Sessions struct {
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+}
+
+func NewSessions(config util.Config, store db.Store, tokenMaker token.Maker) *Sessions {
+	return &Sessions{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+	}
+}
+
+// Authorize returns the payload of the bearer token in the authorization header if it's an access token
+// of an active session. Tokens which don't let their user in return an error wrapping ErrUnauthenticated.
+func (sessions *Sessions) Authorize(ctx context.Context, authorizationHeader string) (*token.Payload, error) {
+	if len(authorizationHeader) == 0 {
+		return nil, unauthenticated(errors.New("authorization header is not provided"))
+	}
+
+	fields := strings.Fields(authorizationHeader)
+	if len(fields) < 2 {
+		return nil, unauthenticated(errors.New("invalid authorization header format"))
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != AuthorizationTypeBearer {
+		return nil, unauthenticated(fmt.Errorf("unsupported authorization type %s", authorizationType))
+	}
+
+	payload, err := sessions.tokenMaker.VerifyToken(fields[1])
+	if err != nil {
+		metrics.ObserveTokenVerificationFailure(err)
+		return nil, unauthenticated(err)
+	}
+
+	// e.g. a long-lived refresh token, or an MFA challenge which must not get past the second factor
+	if payload.Purpose != "" {
+		return nil, unauthenticated(ErrRestrictedToken)
+	}
+
+	// The token must still belong to an active session, so revoked logins are cut off immediately
+	authSession, err := sessions.store.GetAuthSession(ctx, payload.SessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, unauthenticated(errors.New("session not found"))
+		}
+		return nil, fmt.Errorf("cannot get session: %w", err)
+	}
+
+	session := authSession.Session
+	if session.IsBlocked {
+		return nil, unauthenticated(errors.New("blocked session"))
+	}
+
+	if session.Username != payload.Username {
+		return nil, unauthenticated(errors.New("incorrect session user"))
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, unauthenticated(errors.New("expired session"))
+	}
+
+	// Changing the password signs the user out of everywhere
+	if payload.IssuedAt.Before(authSession.PasswordChangedAt) {
+		return nil, unauthenticated(ErrTokenBeforePasswordChange)
+	}
+
+	return payload, nil
+}
+
+type CreateLoginParams struct {
+	User db.User
+	// The methods the user proved who they are with
+	AMR       []string
+	UserAgent string
+	ClientIP  string
+}
+
+// Login is a new session & the tokens of it
+type Login struct {
+	Session        db.Session
+	AccessToken    string
+	AccessPayload  *token.Payload
+	RefreshToken   string
+	RefreshPayload *token.Payload
+}
+
+// CreateLogin starts a session for the user & resets their failed logins
+func (sessions *Sessions) CreateLogin(ctx context.Context, arg CreateLoginParams) (Login, error) {
+	user := arg.User
+	if user.FailedLoginAttempts > 0 {
+		err := sessions.store.ResetFailedLogins(ctx, user.Username)
+		if err != nil {
+			return Login{}, fmt.Errorf("cannot reset failed logins: %w", err)
+		}
+	}
+
+	authTime := time.Now()
+	refreshToken, refreshPayload, err := sessions.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username: user.Username,
+		Role:     user.Role,
+		Duration: sessions.config.RefreshTokenDuration,
+		Purpose:  token.PurposeRefresh,
+		AuthTime: authTime,
+		AMR:      arg.AMR,
+	})
+	if err != nil {
+		return Login{}, fmt.Errorf("cannot create refresh token: %w", err)
+	}
+
+	// Access tokens carry the session of the refresh token, so revoking the session revokes them as well
+	accessToken, accessPayload, err := sessions.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username:  user.Username,
+		Role:      user.Role,
+		SessionID: refreshPayload.SessionID,
+		Duration:  sessions.config.AccessTokenDuration,
+		AuthTime:  authTime,
+		AMR:       arg.AMR,
+	})
+	if err != nil {
+		return Login{}, fmt.Errorf("cannot create access token: %w", err)
+	}
+
+	// The session is identified by the refresh token's ID, so it can be looked up when the token is renewed
+	session, err := sessions.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:        refreshPayload.ID,
+		Username:  user.Username,
+		UserAgent: arg.UserAgent,
+		ClientIp:  arg.ClientIP,
+		IsBlocked: false,
+		ExpiresAt: refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return Login{}, fmt.Errorf("cannot create session: %w", err)
+	}
+
+	login := Login{
+		Session:        session,
+		AccessToken:    accessToken,
+		AccessPayload:  accessPayload,
+		RefreshToken:   refreshToken,
+		RefreshPayload: refreshPayload,
+	}
+	return login, nil
+}
+
+// CreateMFAChallenge returns the token a user with TOTP on gets for the right password. No session is created
+// until the challenge is exchanged, and failed logins aren't reset either, so a known password doesn't help
+// guessing codes.
+func (sessions *Sessions) CreateMFAChallenge(user db.User) (string, *token.Payload, error) {
+	mfaToken, mfaPayload, err := sessions.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username: user.Username,
+		Role:     user.Role,
+		Duration: sessions.config.MFAChallengeDuration,
+		Purpose:  token.PurposeMFAChallenge,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create MFA token: %w", err)
+	}
+	return mfaToken, mfaPayload, nil
+}
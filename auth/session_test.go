@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSessions(t *testing.T, store db.Store) (*Sessions, token.Maker) {
+	config := util.Config{
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		MFAChallengeDuration: time.Minute,
+	}
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+	return NewSessions(config, store, tokenMaker), tokenMaker
+}
+
+func TestAuthorize(t *testing.T) {
+	username := util.RandomOwner()
+	session := db.Session{
+		Username:  username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	blocked := session
+	blocked.IsBlocked = true
+
+	testCases := []struct {
+		name        string
+		purpose     string
+		authType    string
+		buildStubs  func(store *mockdb.MockStore)
+		expectedErr error
+	}{
+		{
+			name:     "OK",
+			authType: AuthorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAuthSessionRow{Session: session}, nil)
+			},
+		},
+		{
+			name:     "UnsupportedAuth",
+			authType: "basic",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrUnauthenticated,
+		},
+		{
+			name:     "RestrictedToken",
+			purpose:  token.PurposeRefresh,
+			authType: AuthorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrRestrictedToken,
+		},
+		{
+			name:     "SessionNotFound",
+			authType: AuthorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAuthSessionRow{}, sql.ErrNoRows)
+			},
+			expectedErr: ErrUnauthenticated,
+		},
+		{
+			name:     "BlockedSession",
+			authType: AuthorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAuthSessionRow{Session: blocked}, nil)
+			},
+			expectedErr: ErrUnauthenticated,
+		},
+		{
+			name:     "PasswordChanged",
+			authType: AuthorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{Session: session, PasswordChangedAt: time.Now().Add(time.Minute)}, nil)
+			},
+			expectedErr: ErrTokenBeforePasswordChange,
+		},
+		{
+			name:     "InternalError",
+			authType: AuthorizationTypeBearer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAuthSessionRow{}, sql.ErrConnDone)
+			},
+			expectedErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			sessions, tokenMaker := newTestSessions(t, store)
+			accessToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+				Username: username,
+				Role:     util.DepositorRole,
+				Duration: time.Minute,
+				Purpose:  tc.purpose,
+			})
+			require.NoError(t, err)
+
+			header := fmt.Sprintf("%s %s", tc.authType, accessToken)
+			payload, err := sessions.Authorize(context.Background(), header)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+				require.Equal(t, username, payload.Username)
+				return
+			}
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == sql.ErrConnDone {
+				require.NotErrorIs(t, err, ErrUnauthenticated)
+			} else {
+				require.ErrorIs(t, err, ErrUnauthenticated)
+			}
+		})
+	}
+}
+
+func TestCreateLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	sessions, tokenMaker := newTestSessions(t, store)
+
+	user, _ := randomUser(t)
+	user.FailedLoginAttempts = 2
+	amr := []string{token.AMRPassword, token.AMROTP}
+
+	store.EXPECT().ResetFailedLogins(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(nil)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Equal(t, "test-agent", arg.UserAgent)
+			require.Equal(t, "10.0.0.1", arg.ClientIp)
+			return db.Session{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+		})
+
+	login, err := sessions.CreateLogin(context.Background(), CreateLoginParams{
+		User:      user,
+		AMR:       amr,
+		UserAgent: "test-agent",
+		ClientIP:  "10.0.0.1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, login.RefreshPayload.ID, login.Session.ID)
+
+	// The access token belongs to the session of the refresh token
+	accessPayload, err := tokenMaker.VerifyToken(login.AccessToken)
+	require.NoError(t, err)
+	require.Empty(t, accessPayload.Purpose)
+	require.Equal(t, login.RefreshPayload.SessionID, accessPayload.SessionID)
+	require.Equal(t, amr, accessPayload.AMR)
+
+	refreshPayload, err := tokenMaker.VerifyToken(login.RefreshToken)
+	require.NoError(t, err)
+	require.Equal(t, token.PurposeRefresh, refreshPayload.Purpose)
+}
+
+func TestCreateMFAChallenge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No session is created & failed logins aren't reset
+	store := mockdb.NewMockStore(ctrl)
+	sessions, tokenMaker := newTestSessions(t, store)
+
+	user, _ := randomUser(t)
+	mfaToken, mfaPayload, err := sessions.CreateMFAChallenge(user)
+	require.NoError(t, err)
+	require.Equal(t, token.PurposeMFAChallenge, mfaPayload.Purpose)
+
+	payload, err := tokenMaker.VerifyToken(mfaToken)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, payload.Username)
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/util"
+)
+
+const commandsUsage = `commands:
+  user create -username u -full-name n -email e [-password p] [-role r] [-email-verified]
+  user reset-password -username u [-password p]
+  user unlock -username u
+  account freeze -id n
+  account unfreeze -id n
+  account close -id n
+  account entries -id n [-limit n]
+  account adjust -id n -amount n -reason text
+  seed [-users n] [-accounts n]
+
+Passwords which are not given are generated and printed.
+Created users get an email to verify their address, unless it's marked verified. Seeded users are verified.
+`
+
+// generatedPasswordLength is more than the 6 characters the API asks for, since these are handed out to people
+const generatedPasswordLength = 12
+
+// CLI runs the commands of bankctl on top of the store
+type CLI struct {
+	store    db.Store
+	out      io.Writer
+	operator string
+}
+
+type command func(ctx context.Context, args []string) error
+
+// Run runs the command named by the first args, e.g. "user create"
+func (cli *CLI) Run(ctx context.Context, args []string) error {
+	commands := map[string]command{
+		"user create":         cli.createUser,
+		"user reset-password": cli.resetPassword,
+		"user unlock":         cli.unlockUser,
+		"account freeze":      cli.freezeAccount,
+		"account unfreeze":    cli.unfreezeAccount,
+		"account close":       cli.closeAccount,
+		"account entries":     cli.listEntries,
+		"account adjust":      cli.adjustAccount,
+	}
+
+	if len(args) >= 1 && args[0] == "seed" {
+		return cli.seed(ctx, args[1:])
+	}
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd(ctx, args[2:])
+		}
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", strings.Join(args, " "), commandsUsage)
+}
+
+func (cli *CLI) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cli.out)
+	return flags
+}
+
+func (cli *CLI) createUser(ctx context.Context, args []string) error {
+	flags := cli.newFlagSet("user create")
+	username := flags.String("username", "", "letters and digits only")
+	fullName := flags.String("full-name", "", "")
+	email := flags.String("email", "", "")
+	password := flags.String("password", "", "generated if empty")
+	role := flags.String("role", util.DepositorRole, "depositor, banker or admin")
+	emailVerified := flags.Bool("email-verified", false, "mark the email as verified instead of sending an email to verify it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" || *fullName == "" || *email == "" {
+		return errors.New("username, full name and email are required")
+	}
+	if !util.IsSupportedRole(*role) {
+		return fmt.Errorf("unsupported role %q", *role)
+	}
+
+	plainPassword, hashedPassword, err := newPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	user, err := cli.insertUser(ctx, db.CreateUserParams{
+		Username:       *username,
+		HashedPassword: hashedPassword,
+		FullName:       *fullName,
+		Email:          *email,
+	}, *emailVerified)
+	if err != nil {
+		return err
+	}
+
+	// New users are depositors, the db sets the role
+	if *role != user.Role {
+		user, err = cli.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+			Username: user.Username,
+			Role:     *role,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot set role of user %s: %w", *username, err)
+		}
+	}
+
+	fmt.Fprintf(cli.out, "created %s user %s\n", user.Role, user.Username)
+	if *password == "" {
+		fmt.Fprintf(cli.out, "password: %s\n", plainPassword)
+	}
+	return nil
+}
+
+// insertUser creates a user like signing up does, so the email worker sends them an email to verify their address.
+// An operator who checked the address already can mark it as verified instead.
+func (cli *CLI) insertUser(ctx context.Context, arg db.CreateUserParams, emailVerified bool) (db.User, error) {
+	if !emailVerified {
+		result, err := cli.store.CreateUserTx(ctx, arg)
+		if err != nil {
+			return db.User{}, fmt.Errorf("cannot create user: %w", err)
+		}
+		return result.User, nil
+	}
+
+	user, err := cli.store.CreateVerifiedUserTx(ctx, arg)
+	if err != nil {
+		return db.User{}, fmt.Errorf("cannot create user: %w", err)
+	}
+	return user, nil
+}
+
+// resetPassword sets a new password and signs the user out everywhere, since the old one may be known to someone else
+func (cli *CLI) resetPassword(ctx context.Context, args []string) error {
+	flags := cli.newFlagSet("user reset-password")
+	username := flags.String("username", "", "")
+	password := flags.String("password", "", "generated if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		return errors.New("username is required")
+	}
+
+	plainPassword, hashedPassword, err := newPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	// The user is unlocked too, since they're usually locked out by then, which is why the password is reset
+	result, err := cli.store.SetPasswordTx(ctx, db.UpdateUserPasswordParams{
+		Username:       *username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user %s not found", *username)
+		}
+		return fmt.Errorf("cannot reset password: %w", err)
+	}
+
+	fmt.Fprintf(cli.out, "reset password of user %s, blocked %d sessions\n", *username, result.BlockedSessions)
+	if *password == "" {
+		fmt.Fprintf(cli.out, "password: %s\n", plainPassword)
+	}
+	return nil
+}
+
+// unlockUser lets a user who was locked out after too many failed logins log in again right away
+func (cli *CLI) unlockUser(ctx context.Context, args []string) error {
+	flags := cli.newFlagSet("user unlock")
+	username := flags.String("username", "", "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		return errors.New("username is required")
+	}
+
+	user, err := cli.store.GetUser(ctx, *username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user %s not found", *username)
+		}
+		return fmt.Errorf("cannot get user: %w", err)
+	}
+
+	err = cli.store.ResetFailedLogins(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("cannot unlock user: %w", err)
+	}
+
+	fmt.Fprintf(cli.out, "unlocked user %s after %d failed logins\n", user.Username, user.FailedLoginAttempts)
+	return nil
+}
+
+// newPassword hashes the password, generating one if it's empty
+func newPassword(password string) (plainPassword string, hashedPassword string, err error) {
+	if password == "" {
+		password, err = util.NewSecurePassword(generatedPasswordLength)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if len(password) < 6 {
+		return "", "", errors.New("password must contain at least 6 characters")
+	}
+
+	hashedPassword, err = util.HashPassword(password)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot hash password: %w", err)
+	}
+	return password, hashedPassword, nil
+}
+
+func (cli *CLI) parseAccountID(name string, args []string) (int64, error) {
+	flags := cli.newFlagSet(name)
+	id := flags.Int64("id", 0, "account ID")
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+
+	if *id < 1 {
+		return 0, errors.New("account ID is required")
+	}
+	return *id, nil
+}
+
+func (cli *CLI) getAccount(ctx context.Context, id int64) (db.Account, error) {
+	account, err := cli.store.GetAccount(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, fmt.Errorf("account [%d] not found", id)
+		}
+		return account, fmt.Errorf("cannot get account: %w", err)
+	}
+	return account, nil
+}
+
+func (cli *CLI) freezeAccount(ctx context.Context, args []string) error {
+	return cli.setAccountStatus(ctx, "account freeze", args, util.AccountActive, util.AccountFrozen)
+}
+
+func (cli *CLI) unfreezeAccount(ctx context.Context, args []string) error {
+	return cli.setAccountStatus(ctx, "account unfreeze", args, util.AccountFrozen, util.AccountActive)
+}
+
+// setAccountStatus moves an account from one status to another
+func (cli *CLI) setAccountStatus(ctx context.Context, name string, args []string, from string, to string) error {
+	id, err := cli.parseAccountID(name, args)
+	if err != nil {
+		return err
+	}
+
+	account, err := cli.store.SetAccountStatusTx(ctx, db.SetAccountStatusTxParams{
+		AccountID: id,
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("account [%d] not found", id)
+		}
+		if errors.Is(err, db.ErrAccountStatus) {
+			return err
+		}
+		return fmt.Errorf("cannot update account: %w", err)
+	}
+
+	printAccount(cli.out, account)
+	return nil
+}
+
+func (cli *CLI) closeAccount(ctx context.Context, args []string) error {
+	id, err := cli.parseAccountID("account close", args)
+	if err != nil {
+		return err
+	}
+
+	account, err := cli.store.CloseAccountTx(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("account [%d] not found", id)
+		}
+		return fmt.Errorf("cannot close account: %w", err)
+	}
+
+	printAccount(cli.out, account)
+	return nil
+}
+
+// listEntries prints the account followed by its latest entries
+func (cli *CLI) listEntries(ctx context.Context, args []string) error {
+	flags := cli.newFlagSet("account entries")
+	id := flags.Int64("id", 0, "account ID")
+	limit := flags.Int("limit", 20, "how many of the latest entries to show")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *id < 1 {
+		return errors.New("account ID is required")
+	}
+	if *limit < 1 {
+		return errors.New("limit must be positive")
+	}
+
+	account, err := cli.getAccount(ctx, *id)
+	if err != nil {
+		return err
+	}
+
+	entries, err := cli.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+		AccountID: *id,
+		Limit:     int32(*limit),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list entries: %w", err)
+	}
+
+	printAccount(cli.out, account)
+	w := tabwriter.NewWriter(cli.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAMOUNT\tCREATED AT")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%d\t%d\t%s\n", entry.ID, entry.Amount, entry.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func (cli *CLI) adjustAccount(ctx context.Context, args []string) error {
+	flags := cli.newFlagSet("account adjust")
+	id := flags.Int64("id", 0, "account ID")
+	amount := flags.Int64("amount", 0, "positive to credit the account, negative to debit it")
+	reason := flags.String("reason", "", "why the balance is changed, e.g. a ticket number")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *id < 1 {
+		return errors.New("account ID is required")
+	}
+	if *amount == 0 {
+		return errors.New("amount must not be zero")
+	}
+	if strings.TrimSpace(*reason) == "" {
+		return errors.New("reason is required")
+	}
+	if cli.operator == "" {
+		return errors.New("operator is required")
+	}
+
+	result, err := cli.store.AdjustTx(ctx, db.AdjustTxParams{
+		AccountID: *id,
+		Amount:    *amount,
+		Reason:    *reason,
+		Operator:  cli.operator,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("account [%d] not found", *id)
+		}
+		return fmt.Errorf("cannot adjust account: %w", err)
+	}
+
+	fmt.Fprintf(cli.out, "adjustment [%d] of %d by %s\n", result.Adjustment.ID, result.Adjustment.Amount, result.Adjustment.Operator)
+	printAccount(cli.out, result.Account)
+	return nil
+}
+
+// seed fills the db with random users, each with funded accounts, for demos & local development
+func (cli *CLI) seed(ctx context.Context, args []string) error {
+	currencies := []string{util.USD, util.EUR, util.CAD}
+
+	flags := cli.newFlagSet("seed")
+	users := flags.Int("users", 3, "how many users to create")
+	accounts := flags.Int("accounts", len(currencies), "how many accounts each user gets, one per currency")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *users < 1 {
+		return errors.New("users must be positive")
+	}
+	if *accounts < 0 || *accounts > len(currencies) {
+		return fmt.Errorf("accounts must be between 0 and %d", len(currencies))
+	}
+
+	w := tabwriter.NewWriter(cli.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tPASSWORD\tACCOUNTS")
+	for i := 0; i < *users; i++ {
+		password, hashedPassword, err := newPassword("")
+		if err != nil {
+			return err
+		}
+
+		// The emails are made up, so there is no point sending anything to them
+		user, err := cli.insertUser(ctx, db.CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		}, true)
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		for _, currency := range currencies[:*accounts] {
+			account, err := cli.store.CreateAccount(ctx, db.CreateAccountParams{
+				Owner:    user.Username,
+				Currency: currency,
+			})
+			if err != nil {
+				return fmt.Errorf("cannot create account: %w", err)
+			}
+
+			_, err = cli.store.DepositTx(ctx, db.DepositTxParams{
+				AccountID: account.ID,
+				Amount:    util.RandomInt(1, 1000),
+				Source:    "seed",
+			})
+			if err != nil {
+				return fmt.Errorf("cannot fund account: %w", err)
+			}
+			ids = append(ids, fmt.Sprintf("%d (%s)", account.ID, currency))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", user.Username, password, strings.Join(ids, ", "))
+	}
+	return w.Flush()
+}
+
+func printAccount(out io.Writer, account db.Account) {
+	fmt.Fprintf(out, "account [%d] of %s: %d %s, %s\n", account.ID, account.Owner, account.Balance, account.Currency, account.Status)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAccount(status string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+		Status:   status,
+	}
+}
+
+func TestCLI(t *testing.T) {
+	account := randomAccount(util.AccountActive)
+	frozen := account
+	frozen.Status = util.AccountFrozen
+
+	testCases := []struct {
+		name       string
+		args       []string
+		buildStubs func(store *mockdb.MockStore)
+		checkRun   func(t *testing.T, out string, err error)
+	}{
+		{
+			name: "CreateUser",
+			args: []string{"user", "create", "-username", "alice", "-full-name", "Alice", "-email", "alice@email.com", "-role", util.BankerRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				// Like a signup, so the user gets an email to verify their address
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.CreateUserTxResult, error) {
+						require.Equal(t, "alice", arg.Username)
+						require.Equal(t, "alice@email.com", arg.Email)
+						require.NotEmpty(t, arg.HashedPassword)
+						return db.CreateUserTxResult{User: db.User{Username: arg.Username, Role: util.DepositorRole}}, nil
+					})
+				store.EXPECT().CreateVerifiedUserTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Eq(db.UpdateUserRoleParams{Username: "alice", Role: util.BankerRole})).
+					Times(1).
+					Return(db.User{Username: "alice", Role: util.BankerRole}, nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "created banker user alice")
+				require.Contains(t, out, "password: ") // Generated, since none was given
+			},
+		},
+		{
+			name: "CreateUserEmailVerified",
+			args: []string{"user", "create", "-username", "alice", "-full-name", "Alice", "-email", "alice@email.com", "-email-verified"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					CreateVerifiedUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+						require.Equal(t, "alice@email.com", arg.Email)
+						return db.User{Username: arg.Username, Email: arg.Email, Role: util.DepositorRole, IsEmailVerified: true}, nil
+					})
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "created depositor user alice")
+			},
+		},
+		{
+			name: "CreateUserUnsupportedRole",
+			args: []string{"user", "create", "-username", "alice", "-full-name", "Alice", "-email", "alice@email.com", "-role", "boss"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "ResetPassword",
+			args: []string{"user", "reset-password", "-username", "alice", "-password", "secret123"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserPasswordParams) (db.SetPasswordTxResult, error) {
+						require.Equal(t, "alice", arg.Username)
+						require.NoError(t, util.CheckPassword("secret123", arg.HashedPassword))
+						return db.SetPasswordTxResult{User: db.User{Username: arg.Username}, BlockedSessions: 2}, nil
+					})
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "blocked 2 sessions")
+				require.NotContains(t, out, "secret123")
+			},
+		},
+		{
+			name: "ResetPasswordUserNotFound",
+			args: []string{"user", "reset-password", "-username", "nobody"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.SetPasswordTxResult{}, sql.ErrNoRows)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.EqualError(t, err, "user nobody not found")
+			},
+		},
+		{
+			name: "UnlockUser",
+			args: []string{"user", "unlock", "-username", "alice"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq("alice")).
+					Times(1).
+					Return(db.User{Username: "alice", FailedLoginAttempts: 7}, nil)
+				store.EXPECT().ResetFailedLogins(gomock.Any(), gomock.Eq("alice")).Times(1).Return(nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "unlocked user alice after 7 failed logins")
+			},
+		},
+		{
+			name: "UnlockUserNotFound",
+			args: []string{"user", "unlock", "-username", "nobody"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().ResetFailedLogins(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.EqualError(t, err, "user nobody not found")
+			},
+		},
+		{
+			name: "FreezeAccount",
+			args: []string{"account", "freeze", "-id", "7"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetAccountStatusTx(gomock.Any(), gomock.Eq(db.SetAccountStatusTxParams{
+						AccountID: 7,
+						From:      util.AccountActive,
+						To:        util.AccountFrozen,
+					})).
+					Times(1).
+					Return(frozen, nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, util.AccountFrozen)
+			},
+		},
+		{
+			name: "UnfreezeActiveAccount",
+			args: []string{"account", "unfreeze", "-id", "7"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("%w: account [7] is active, not frozen", db.ErrAccountStatus))
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.ErrorIs(t, err, db.ErrAccountStatus)
+			},
+		},
+		{
+			name: "FreezeAccountNotFound",
+			args: []string{"account", "freeze", "-id", "7"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.EqualError(t, err, "account [7] not found")
+			},
+		},
+		{
+			name: "CloseAccount",
+			args: []string{"account", "close", "-id", "7"},
+			buildStubs: func(store *mockdb.MockStore) {
+				closed := account
+				closed.Balance = 0
+				closed.Status = util.AccountClosed
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Eq(int64(7))).Times(1).Return(closed, nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, util.AccountClosed)
+			},
+		},
+		{
+			name: "ListEntries",
+			args: []string{"account", "entries", "-id", "7", "-limit", "2"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(7))).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Eq(db.ListAccountEntriesParams{AccountID: 7, Limit: 2})).
+					Times(1).
+					Return([]db.Entry{{ID: 11, AccountID: 7, Amount: -25}, {ID: 10, AccountID: 7, Amount: 40}}, nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "-25")
+				require.Contains(t, out, "40")
+			},
+		},
+		{
+			name: "AdjustAccount",
+			args: []string{"account", "adjust", "-id", "7", "-amount", "-15", "-reason", "refund of a duplicated fee"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.AdjustTxParams{
+					AccountID: 7,
+					Amount:    -15,
+					Reason:    "refund of a duplicated fee",
+					Operator:  "operator",
+				}
+				store.EXPECT().
+					AdjustTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.AdjustTxResult{Account: account, Adjustment: db.Adjustment{ID: 1, Amount: -15, Operator: "operator"}}, nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "by operator")
+			},
+		},
+		{
+			name: "AdjustAccountWithoutReason",
+			args: []string{"account", "adjust", "-id", "7", "-amount", "15"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().AdjustTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.EqualError(t, err, "reason is required")
+			},
+		},
+		{
+			name: "Seed",
+			args: []string{"seed", "-users", "2", "-accounts", "1"},
+			buildStubs: func(store *mockdb.MockStore) {
+				// The made up emails are marked as verified, so nothing is sent to them
+				store.EXPECT().
+					CreateVerifiedUserTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+						require.NotEmpty(t, arg.Email)
+						return db.User{Username: arg.Username, Email: arg.Email, IsEmailVerified: true}, nil
+					})
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ context.Context, arg db.CreateAccountParams) (db.Account, error) {
+						require.Equal(t, util.USD, arg.Currency)
+						return db.Account{ID: 1, Owner: arg.Owner, Currency: arg.Currency}, nil
+					})
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(2).Return(db.DepositTxResult{}, nil)
+			},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, out, "1 (USD)")
+			},
+		},
+		{
+			name:       "UnknownCommand",
+			args:       []string{"account", "delete", "-id", "7"},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkRun: func(t *testing.T, out string, err error) {
+				require.ErrorContains(t, err, "unknown command")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var out bytes.Buffer
+			cli := &CLI{store: store, out: &out, operator: "operator"}
+			err := cli.Run(context.Background(), tc.args)
+			tc.checkRun(t, out.String(), err)
+		})
+	}
+}
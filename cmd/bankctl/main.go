@@ -0,0 +1,73 @@
+// Command bankctl runs support tasks against the bank db, so operators don't have to edit it by hand.
+//
+// Usage:
+//
+//	bankctl [-config dir] [-operator name] <command> [flags]
+//
+// Run bankctl -h for the list of commands.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/util"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+func main() {
+	configPath := flag.String("config", ".", "directory of app.env")
+	operator := flag.String("operator", defaultOperator(), "who runs the command, recorded on manual adjustments")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: bankctl [-config dir] [-operator name] <command> [flags]\n\n%s\nglobal flags:\n", commandsUsage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	config, err := util.LoadConfig(*configPath)
+	if err != nil {
+		fatal("cannot load config:", err)
+	}
+
+	// Operators read the output, so the store only speaks up about problems
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).Level(zerolog.WarnLevel)
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		fatal("cannot connect to db:", err)
+	}
+	defer conn.Close()
+
+	store := db.NewStore(conn, logger, db.RetryPolicy{
+		MaxRetries: config.TxMaxRetries,
+		Backoff:    config.TxRetryBackoff,
+	})
+
+	cli := &CLI{
+		store:    store,
+		out:      os.Stdout,
+		operator: *operator,
+	}
+	if err := cli.Run(context.Background(), flag.Args()); err != nil {
+		conn.Close()
+		fatal(err)
+	}
+}
+
+func defaultOperator() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+func fatal(v ...any) {
+	fmt.Fprintln(os.Stderr, v...)
+	os.Exit(1)
+}
@@ -0,0 +1,2 @@
+drop table if exists deposits;
+drop table if exists withdrawals;
\ No newline at end of file
@@ -0,0 +1,31 @@
+CREATE TABLE "deposits" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "source" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "withdrawals" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "destination" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "deposits" ("account_id");
+
+CREATE INDEX ON "withdrawals" ("account_id");
+
+COMMENT ON COLUMN "deposits"."amount" IS 'must be positive';
+
+COMMENT ON COLUMN "deposits"."source" IS 'external reference of where the money comes from';
+
+COMMENT ON COLUMN "withdrawals"."amount" IS 'must be positive';
+
+COMMENT ON COLUMN "withdrawals"."destination" IS 'external reference of where the money goes to';
+
+ALTER TABLE "deposits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "withdrawals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,16 @@
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+-- Every transfer so far moved money between accounts of the same currency
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_exchange_rate_check" CHECK ("exchange_rate" > 0);
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive, in the currency of the from account';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'must be positive, in the currency of the to account';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of the to currency per unit of the from currency';
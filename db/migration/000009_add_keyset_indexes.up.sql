@@ -0,0 +1,8 @@
+-- Listings are paged by (created_at, id), so these let each page start right after the previous one
+CREATE INDEX "accounts_owner_created_at_id_idx" ON "accounts" ("owner", "created_at", "id");
+
+CREATE INDEX "entries_account_id_created_at_id_idx" ON "entries" ("account_id", "created_at", "id");
+
+CREATE INDEX "transfers_from_account_id_created_at_id_idx" ON "transfers" ("from_account_id", "created_at", "id");
+
+CREATE INDEX "transfers_to_account_id_created_at_id_idx" ON "transfers" ("to_account_id", "created_at", "id");
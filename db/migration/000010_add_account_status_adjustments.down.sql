@@ -0,0 +1,5 @@
+drop table if exists adjustments;
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_status_check";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "status";
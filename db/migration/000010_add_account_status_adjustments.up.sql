@@ -0,0 +1,24 @@
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'frozen', 'closed'));
+
+COMMENT ON COLUMN "accounts"."status" IS 'only active accounts move money';
+
+CREATE TABLE "adjustments" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "reason" varchar NOT NULL,
+  "operator" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "adjustments" ("account_id");
+
+ALTER TABLE "adjustments" ADD CONSTRAINT "adjustments_amount_check" CHECK ("amount" <> 0);
+
+COMMENT ON COLUMN "adjustments"."amount" IS 'positive to credit the account, negative to debit it';
+
+COMMENT ON COLUMN "adjustments"."reason" IS 'why an operator changed the balance by hand';
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
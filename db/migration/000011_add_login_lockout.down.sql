@@ -0,0 +1,3 @@
+ALTER TABLE "users" DROP COLUMN IF EXISTS "locked_until";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "failed_login_attempts";
@@ -0,0 +1,25 @@
+ALTER TABLE "users" ADD COLUMN "is_email_verified" bool NOT NULL DEFAULT false;
+
+CREATE TABLE "verify_emails" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "secret_code_hash" varchar NOT NULL DEFAULT '',
+  "is_used" bool NOT NULL DEFAULT false,
+  "send_attempts" int NOT NULL DEFAULT 0,
+  "next_send_at" timestamptz NOT NULL DEFAULT (now()),
+  "sent_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "verify_emails" ("username");
+
+-- The email worker only ever looks for emails still to be sent
+CREATE INDEX ON "verify_emails" ("next_send_at") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "verify_emails"."secret_code_hash" IS 'sha256 of the code in the email, set once the email is about to be sent';
+
+COMMENT ON COLUMN "verify_emails"."next_send_at" IS 'the email worker leaves the email alone until then, so a failed send is retried later';
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,20 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar NOT NULL DEFAULT '';
+ALTER TABLE "users" ADD COLUMN "totp_enabled" bool NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN "totp_last_step" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'TOTP secret sealed with the TOTP encryption key, set on enrollment & only used once enabled';
+
+COMMENT ON COLUMN "users"."totp_last_step" IS 'time step of the last TOTP code used, so no code works twice';
+
+CREATE TABLE "recovery_codes" (
+  "code_hash" varchar PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "recovery_codes" ("username");
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha256 of the recovery code shown to the user, which is never stored';
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
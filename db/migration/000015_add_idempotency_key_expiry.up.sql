@@ -0,0 +1,2 @@
+-- Expired keys are deleted by age
+CREATE INDEX "idempotency_keys_created_at_idx" ON "idempotency_keys" ("created_at");
@@ -0,0 +1,15 @@
+CREATE TABLE "password_reset_emails" (
+  "id" bigserial PRIMARY KEY,
+  "email" varchar NOT NULL,
+  "send_attempts" int NOT NULL DEFAULT 0,
+  "next_send_at" timestamptz NOT NULL DEFAULT (now()),
+  "sent_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- The email worker only ever looks for emails still to be sent
+CREATE INDEX ON "password_reset_emails" ("next_send_at") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "password_reset_emails"."email" IS 'as asked for, so not every row belongs to a user';
+
+COMMENT ON COLUMN "password_reset_emails"."sent_at" IS 'also set when no user has the email, since there is nothing to send then';
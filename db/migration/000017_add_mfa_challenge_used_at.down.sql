@@ -0,0 +1 @@
+ALTER TABLE "users" DROP COLUMN IF EXISTS "mfa_challenge_used_at";
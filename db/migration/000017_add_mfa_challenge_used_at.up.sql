@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "mfa_challenge_used_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z';
+
+COMMENT ON COLUMN "users"."mfa_challenge_used_at" IS 'issue time of the last MFA challenge exchanged for a login, so no challenge works twice';
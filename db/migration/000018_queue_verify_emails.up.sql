@@ -0,0 +1,13 @@
+-- Users created before 000013 were left unverified with no email to verify their address.
+-- Every unverified user without a pending email gets one, which the email worker sends.
+INSERT INTO verify_emails (username, email)
+SELECT u.username, u.email
+FROM users u
+WHERE NOT u.is_email_verified
+  AND NOT EXISTS (
+    SELECT 1 FROM verify_emails v
+    WHERE v.username = u.username
+      AND v.email = u.email
+      AND NOT v.is_used
+      AND (v.sent_at IS NULL OR v.expired_at > now())
+  );
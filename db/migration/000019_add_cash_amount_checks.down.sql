@@ -0,0 +1,3 @@
+ALTER TABLE "withdrawals" DROP CONSTRAINT IF EXISTS "withdrawals_amount_check";
+
+ALTER TABLE "deposits" DROP CONSTRAINT IF EXISTS "deposits_amount_check";
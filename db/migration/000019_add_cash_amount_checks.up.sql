@@ -0,0 +1,4 @@
+-- Amounts are positive, the direction is told by the table
+ALTER TABLE "deposits" ADD CONSTRAINT "deposits_amount_check" CHECK ("amount" > 0);
+
+ALTER TABLE "withdrawals" ADD CONSTRAINT "withdrawals_amount_check" CHECK ("amount" > 0);
@@ -0,0 +1,113 @@
+// Package migration embeds the schema migrations, so the binary can migrate the db itself
+// and knows which version the db should be at
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Reasons for the binary not to serve against a db
+var (
+	ErrNotMigrated = errors.New("db has not been migrated")
+	ErrDirty       = errors.New("db is dirty, a migration failed halfway")
+	ErrNewerSchema = errors.New("db schema is newer than the binary")
+	ErrOldSchema   = errors.New("db schema is older than the binary")
+)
+
+// LatestVersion is the version of the newest migration, i.e. the one a fully migrated db is at
+func LatestVersion() (uint, error) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, file := range files {
+		prefix, _, found := strings.Cut(file, "_")
+		if !found {
+			return 0, fmt.Errorf("migration %s has no version", file)
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has an invalid version: %w", file, err)
+		}
+
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest, nil
+}
+
+// CheckVersion tells whether a db at the version is safe to serve, i.e. it's clean and at the latest migration
+func CheckVersion(version uint, dirty bool) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("%w: version %d", ErrDirty, version)
+	case version > latest:
+		return fmt.Errorf("%w: version %d, expected %d", ErrNewerSchema, version, latest)
+	case version < latest:
+		return fmt.Errorf("%w: version %d, expected %d", ErrOldSchema, version, latest)
+	}
+	return nil
+}
+
+// New creates a migrator for the db. Migrations are read from sourceURL (e.g. file://db/migration),
+// or from the embedded files if it's empty.
+func New(sourceURL string, databaseURL string) (*migrate.Migrate, error) {
+	if sourceURL != "" {
+		return migrate.New(sourceURL, databaseURL)
+	}
+
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+	return migrate.NewWithSourceInstance("iofs", source, databaseURL)
+}
+
+// Version is the version of the db, returning ErrNotMigrated if no migration has been applied
+func Version(m *migrate.Migrate) (version uint, dirty bool, err error) {
+	version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		err = ErrNotMigrated
+	}
+	return
+}
+
+// Up applies all the migrations not applied yet. A db which is already up to date is fine.
+func Up(m *migrate.Migrate) error {
+	err := m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Check makes sure the db can be served by this binary
+func Check(m *migrate.Migrate) error {
+	version, dirty, err := Version(m)
+	if err != nil {
+		return err
+	}
+	return CheckVersion(version, dirty)
+}
@@ -0,0 +1,85 @@
+package migration
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	// Migrations are numbered one after another
+	version, err := LatestVersion()
+	require.NoError(t, err)
+	require.Equal(t, uint(len(files)), version)
+}
+
+func TestCheckVersion(t *testing.T) {
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		version     uint
+		dirty       bool
+		expectedErr error
+	}{
+		{
+			name:    "Latest",
+			version: latest,
+		},
+		{
+			name:        "Dirty",
+			version:     latest,
+			dirty:       true,
+			expectedErr: ErrDirty,
+		},
+		{
+			name:        "Newer",
+			version:     latest + 1,
+			expectedErr: ErrNewerSchema,
+		},
+		{
+			name:        "Older",
+			version:     latest - 1,
+			expectedErr: ErrOldSchema,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckVersion(tc.version, tc.dirty)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestEmbeddedSource(t *testing.T) {
+	source, err := iofs.New(FS, ".")
+	require.NoError(t, err)
+	defer source.Close()
+
+	first, err := source.First()
+	require.NoError(t, err)
+	require.Equal(t, uint(1), first)
+
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	// Every migration can be reached from the first one, up to the latest
+	version := first
+	for version < latest {
+		version, err = source.Next(version)
+		require.NoError(t, err)
+	}
+	require.Equal(t, latest, version)
+}
@@ -0,0 +1,10 @@
+-- name: CreateAdjustment :one
+insert into adjustments (account_id, amount, reason, operator)
+values ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetAdjustment :one
+select *
+from adjustments
+where id = $1
+limit 1;
@@ -0,0 +1,10 @@
+-- name: CreateDeposit :one
+insert into deposits (account_id, amount, source)
+values ($1, $2, $3)
+RETURNING *;
+
+-- name: GetDeposit :one
+select *
+from deposits
+where id = $1
+limit 1;
@@ -15,4 +15,24 @@ from entries
 where account_id = $1 
 order by id
 limit $2
-offset $3;
\ No newline at end of file
+offset $3;
+
+-- name: ListAccountEntries :many
+select *
+from entries
+where account_id = sqlc.arg(account_id)
+  and (sqlc.narg(start_time)::timestamptz is null or created_at >= sqlc.narg(start_time))
+  and (sqlc.narg(end_time)::timestamptz is null or created_at < sqlc.narg(end_time))
+  and (
+    sqlc.narg(direction)::text is null
+    or (sqlc.narg(direction) = 'incoming' and amount > 0)
+    or (sqlc.narg(direction) = 'outgoing' and amount < 0)
+  )
+  and (sqlc.narg(min_amount)::bigint is null or abs(amount) >= sqlc.narg(min_amount))
+  and (sqlc.narg(max_amount)::bigint is null or abs(amount) <= sqlc.narg(max_amount))
+  and (
+    sqlc.narg(before_created_at)::timestamptz is null
+    or (created_at, id) < (sqlc.narg(before_created_at), sqlc.narg(before_id)::bigint)
+  )
+order by created_at desc, id desc
+limit sqlc.arg('limit');
@@ -0,0 +1,41 @@
+-- name: CreateIdempotencyKey :one
+-- Takes the key, unless another request holds it. A key is free again once it expired, or once the request holding it
+-- was abandoned in progress, e.g. the server crashed, and the same request is retried.
+INSERT INTO idempotency_keys (
+  username,
+  key,
+  request_hash
+) VALUES (
+  sqlc.arg(username), sqlc.arg(key), sqlc.arg(request_hash)
+)
+ON CONFLICT (username, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+  response_status = 0,
+  response_body = '',
+  created_at = now()
+WHERE idempotency_keys.created_at < sqlc.arg(expired_before)
+  OR (
+    idempotency_keys.response_status = 0 AND
+    idempotency_keys.request_hash = EXCLUDED.request_hash AND
+    idempotency_keys.created_at < sqlc.arg(abandoned_before)
+  )
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2
+LIMIT 1;
+
+-- name: UpdateIdempotencyKeyResponse :one
+UPDATE idempotency_keys
+SET response_status = $3, response_body = $4
+WHERE username = $1 AND key = $2
+RETURNING *;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE username = $1 AND key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
@@ -0,0 +1,54 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+  token_hash,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: UsePasswordResetToken :one
+-- Marks the token as used, as long as it's neither used nor expired yet, so it works only once
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING *;
+
+-- name: CreatePasswordResetEmail :exec
+INSERT INTO password_reset_emails (
+  email
+) VALUES (
+  $1
+);
+
+-- name: ClaimPasswordResetEmail :one
+-- Takes the next email due to be sent. Like ClaimVerifyEmail, the email isn't due again until retry_seconds later,
+-- twice as long after every attempt, so other workers leave it alone while it's sent & it's retried if the send fails.
+UPDATE password_reset_emails
+SET
+  send_attempts = send_attempts + 1,
+  next_send_at = now() + make_interval(secs => sqlc.arg(retry_seconds)::float8 * power(2, LEAST(send_attempts, 30)))
+WHERE id = (
+  SELECT id FROM password_reset_emails
+  WHERE sent_at IS NULL
+    AND send_attempts < sqlc.arg(max_attempts)::int
+    AND next_send_at <= now()
+  ORDER BY next_send_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkPasswordResetEmailSent :exec
+UPDATE password_reset_emails
+SET sent_at = now()
+WHERE id = $1;
+
+-- name: InvalidatePasswordResetTokens :exec
+-- Marks every token of the user which isn't used yet as used, so none of them works once the password changed
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1
+  AND used_at IS NULL;
@@ -0,0 +1,54 @@
+-- name: SetTOTPSecret :one
+-- Starts over an enrollment, which is only possible while TOTP is not enabled yet
+UPDATE users
+SET totp_secret = sqlc.arg(totp_secret), totp_last_step = 0
+WHERE username = sqlc.arg(username) AND NOT totp_enabled
+RETURNING *;
+
+-- name: EnableTOTP :one
+-- The step of the code which confirmed the enrollment counts as used
+UPDATE users
+SET totp_enabled = true, totp_last_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND NOT totp_enabled AND totp_secret <> ''
+RETURNING *;
+
+-- name: DisableTOTP :one
+UPDATE users
+SET totp_enabled = false, totp_secret = '', totp_last_step = 0
+WHERE username = $1
+RETURNING *;
+
+-- name: UseTOTPStep :one
+-- Only succeeds for a step later than the last one used, so a code can't be replayed
+UPDATE users
+SET totp_last_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND totp_enabled AND totp_last_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+  code_hash,
+  username
+) VALUES (
+  $1, $2
+);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :one
+-- Marks the code as used, as long as it belongs to the user & isn't used yet, so it works only once
+UPDATE recovery_codes
+SET used_at = now()
+WHERE code_hash = sqlc.arg(code_hash)
+  AND username = sqlc.arg(username)
+  AND used_at IS NULL
+RETURNING *;
+
+-- name: UseMFAChallenge :one
+-- Only succeeds for a challenge issued later than the last one used, so a challenge can't be replayed
+UPDATE users
+SET mfa_challenge_used_at = sqlc.arg(issued_at)
+WHERE username = sqlc.arg(username) AND mfa_challenge_used_at < sqlc.arg(issued_at)
+RETURNING *;
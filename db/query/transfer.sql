@@ -1,6 +1,6 @@
 -- name: CreateTransfer :one
-insert into transfers (from_account_id, to_account_id, amount) 
-VALUES ($1, $2, $3)
+insert into transfers (from_account_id, to_account_id, amount, to_amount, exchange_rate) 
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetTransfer :one
@@ -15,4 +15,34 @@ from transfers
 where from_account_id = $1 and to_account_id = $2
 order by id
 limit $3
-offset $4;
\ No newline at end of file
+offset $4;
+
+-- name: ListAccountTransfers :many
+-- Newest first. Amounts are compared in the currency of the given account.
+select *
+from transfers
+where (
+    (from_account_id = sqlc.arg(account_id) and coalesce(sqlc.narg(direction)::text, 'outgoing') = 'outgoing')
+    or (to_account_id = sqlc.arg(account_id) and coalesce(sqlc.narg(direction)::text, 'incoming') = 'incoming')
+  )
+  and (
+    sqlc.narg(counterparty_account_id)::bigint is null
+    or from_account_id = sqlc.narg(counterparty_account_id)
+    or to_account_id = sqlc.narg(counterparty_account_id)
+  )
+  and (sqlc.narg(start_time)::timestamptz is null or created_at >= sqlc.narg(start_time))
+  and (sqlc.narg(end_time)::timestamptz is null or created_at < sqlc.narg(end_time))
+  and (
+    sqlc.narg(min_amount)::bigint is null
+    or (case when from_account_id = sqlc.arg(account_id) then amount else to_amount end) >= sqlc.narg(min_amount)
+  )
+  and (
+    sqlc.narg(max_amount)::bigint is null
+    or (case when from_account_id = sqlc.arg(account_id) then amount else to_amount end) <= sqlc.narg(max_amount)
+  )
+  and (
+    sqlc.narg(before_created_at)::timestamptz is null
+    or (created_at, id) < (sqlc.narg(before_created_at), sqlc.narg(before_id)::bigint)
+  )
+order by created_at desc, id desc
+limit sqlc.arg('limit');
@@ -10,4 +10,48 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1  LIMIT 1;
\ No newline at end of file
+WHERE username = $1  LIMIT 1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = sqlc.arg(hashed_password), password_changed_at = now()
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: RecordFailedLogin :one
+-- From max_attempts failed logins in a row on, every failed one locks the user for twice as long as the one before,
+-- starting from lockout_seconds & up to max_lockout_seconds
+UPDATE users
+SET
+  failed_login_attempts = failed_login_attempts + 1,
+  locked_until = CASE
+    WHEN failed_login_attempts + 1 >= sqlc.arg(max_attempts)::int THEN now() + make_interval(secs => LEAST(
+      sqlc.arg(lockout_seconds)::float8 * power(2, LEAST(failed_login_attempts + 1 - sqlc.arg(max_attempts)::int, 30)),
+      sqlc.arg(max_lockout_seconds)::float8
+    ))
+    ELSE locked_until
+  END
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = '0001-01-01 00:00:00Z'
+WHERE username = $1;
+
+-- name: VerifyUserEmail :one
+-- The email is only verified if the user still has the email the code was sent to
+UPDATE users
+SET is_email_verified = true
+WHERE username = sqlc.arg(username) AND email = sqlc.arg(email)
+RETURNING *;
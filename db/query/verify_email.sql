@@ -0,0 +1,43 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: ClaimVerifyEmail :one
+-- Takes the next email due to be sent & sets its code. The email isn't due again until retry_seconds later,
+-- twice as long after every attempt, so other workers leave it alone while it's sent & it's retried if the send fails.
+UPDATE verify_emails
+SET
+  secret_code_hash = sqlc.arg(secret_code_hash),
+  send_attempts = send_attempts + 1,
+  next_send_at = now() + make_interval(secs => sqlc.arg(retry_seconds)::float8 * power(2, LEAST(send_attempts, 30))),
+  expired_at = now() + make_interval(secs => sqlc.arg(expire_seconds)::float8)
+WHERE id = (
+  SELECT id FROM verify_emails
+  WHERE sent_at IS NULL
+    AND send_attempts < sqlc.arg(max_attempts)::int
+    AND next_send_at <= now()
+  ORDER BY next_send_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkVerifyEmailSent :exec
+UPDATE verify_emails
+SET sent_at = now()
+WHERE id = $1;
+
+-- name: UseVerifyEmail :one
+-- Marks the code as used, as long as it's right & neither used nor expired yet
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1
+  AND secret_code_hash = $2
+  AND secret_code_hash != ''
+  AND is_used = false
+  AND expired_at > now()
+RETURNING *;
@@ -0,0 +1,10 @@
+-- name: CreateWithdrawal :one
+insert into withdrawals (account_id, amount, destination)
+values ($1, $2, $3)
+RETURNING *;
+
+-- name: GetWithdrawal :one
+select *
+from withdrawals
+where id = $1
+limit 1;
@@ -26,6 +26,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, util.AccountActive, account.Status)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -79,23 +80,34 @@ func TestDeleteAccount(t *testing.T) {
 }
 
 func TestListAccount(t *testing.T) {
-	var lastAccount Account
-	for i := 0; i < 10; i++ {
-		lastAccount = createRandomAccount(t)
+	user := createRandomUser(t)
+
+	// Owners have at most one account per currency
+	var accounts []Account
+	for _, currency := range []string{util.USD, util.EUR, util.CAD} {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  util.RandomMoney(),
+			Currency: currency,
+		})
+		require.NoError(t, err)
+		accounts = append(accounts, account)
 	}
 
 	arg := ListAccountsParams{
-		Owner:  lastAccount.Owner,
-		Limit:  5,
-		Offset: 0,
+		Owner: user.Username,
+		Limit: 2,
 	}
 
-	accounts, err := testQueries.ListAccounts(context.Background(), arg)
+	page, err := testQueries.ListAccounts(context.Background(), arg)
 	require.NoError(t, err)
-	require.NotEmpty(t, accounts)
+	require.Equal(t, accounts[:2], page)
 
-	for _, account := range accounts {
-		require.NotEmpty(t, account)
-		require.Equal(t, lastAccount.Owner, account.Owner)
-	}
+	// The next page starts right after the last account of the previous one
+	arg.AfterCreatedAt = sql.NullTime{Time: page[1].CreatedAt, Valid: true}
+	arg.AfterID = sql.NullInt64{Int64: page[1].ID, Valid: true}
+
+	page, err = testQueries.ListAccounts(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, accounts[2:], page)
 }
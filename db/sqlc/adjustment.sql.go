@@ -0,0 +1,63 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: adjustment.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createAdjustment = `-- name: CreateAdjustment :one
+insert into adjustments (account_id, amount, reason, operator)
+values ($1, $2, $3, $4)
+RETURNING id, account_id, amount, reason, operator, created_at
+`
+
+type CreateAdjustmentParams struct {
+	AccountID int64  `json:"account_id"`
+	Amount    int64  `json:"amount"`
+	Reason    string `json:"reason"`
+	Operator  string `json:"operator"`
+}
+
+func (q *Queries) CreateAdjustment(ctx context.Context, arg CreateAdjustmentParams) (Adjustment, error) {
+	row := q.db.QueryRowContext(ctx, createAdjustment,
+		arg.AccountID,
+		arg.Amount,
+		arg.Reason,
+		arg.Operator,
+	)
+	var i Adjustment
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.Reason,
+		&i.Operator,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAdjustment = `-- name: GetAdjustment :one
+select id, account_id, amount, reason, operator, created_at
+from adjustments
+where id = $1
+limit 1
+`
+
+func (q *Queries) GetAdjustment(ctx context.Context, id int64) (Adjustment, error) {
+	row := q.db.QueryRowContext(ctx, getAdjustment, id)
+	var i Adjustment
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.Reason,
+		&i.Operator,
+		&i.CreatedAt,
+	)
+	return i, err
+}
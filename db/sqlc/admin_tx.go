@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kvnyijia/bank-app/util"
+)
+
+var (
+	// ErrAccountNotEmpty is returned when closing an account which still holds or owes money
+	ErrAccountNotEmpty = errors.New("account balance is not zero")
+	// ErrAccountStatus is returned when an account isn't in the status a change of status starts from
+	ErrAccountStatus = errors.New("unexpected account status")
+)
+
+type AdjustTxParams struct {
+	AccountID int64  `json:"account_id"`
+	Amount    int64  `json:"amount"`
+	Reason    string `json:"reason"`
+	Operator  string `json:"operator"`
+}
+
+type AdjustTxResult struct {
+	Adjustment Adjustment `json:"adjustment"`
+	Account    Account    `json:"account"`
+	Entry      Entry      `json:"entry"`
+}
+
+// AdjustTx lets an operator correct the balance of an account by hand, recording why.
+// Frozen accounts can be adjusted, but closed ones can't. The balance still has to stay above the overdraft limit.
+func (store *SQLStore) AdjustTx(ctx context.Context, arg AdjustTxParams) (AdjustTxResult, error) {
+	var result AdjustTxResult
+
+	err := store.execTx(ctx, "AdjustTx", nil, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status == util.AccountClosed {
+			return &AccountInactiveError{AccountID: account.ID, Status: account.Status}
+		}
+
+		result.Adjustment, err = q.CreateAdjustment(ctx, CreateAdjustmentParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			Reason:    arg.Reason,
+			Operator:  arg.Operator,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount,
+		})
+		return err
+	})
+	return result, asInsufficientFunds(err)
+}
+
+// CloseAccountTx closes an account for good. Its balance must be zero, so no money gets stuck in it.
+func (store *SQLStore) CloseAccountTx(ctx context.Context, accountID int64) (Account, error) {
+	var result Account
+
+	err := store.execTx(ctx, "CloseAccountTx", nil, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Balance != 0 {
+			return fmt.Errorf("%w: account [%d] has %d", ErrAccountNotEmpty, account.ID, account.Balance)
+		}
+
+		result, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     accountID,
+			Status: util.AccountClosed,
+		})
+		return err
+	})
+	return result, err
+}
+
+type SetAccountStatusTxParams struct {
+	AccountID int64  `json:"account_id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// SetAccountStatusTx moves an account from one status to another. The account is locked while its status is checked,
+// so a concurrent change, e.g. closing it, can't be overwritten.
+func (store *SQLStore) SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (Account, error) {
+	var result Account
+
+	err := store.execTx(ctx, "SetAccountStatusTx", nil, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status != arg.From {
+			return fmt.Errorf("%w: account [%d] is %s, not %s", ErrAccountStatus, account.ID, account.Status, arg.From)
+		}
+
+		result, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     arg.AccountID,
+			Status: arg.To,
+		})
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account := createRandomAccount(t)
+	frozen, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account.ID,
+		Status: util.AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	// Operators can still fix the balance of a frozen account
+	result, err := store.AdjustTx(context.Background(), AdjustTxParams{
+		AccountID: frozen.ID,
+		Amount:    -account.Balance,
+		Reason:    "chargeback #42",
+		Operator:  "ops",
+	})
+	require.NoError(t, err)
+
+	require.NotZero(t, result.Adjustment.ID)
+	require.Equal(t, "chargeback #42", result.Adjustment.Reason)
+	require.Equal(t, "ops", result.Adjustment.Operator)
+	require.Equal(t, -account.Balance, result.Entry.Amount)
+	require.Zero(t, result.Account.Balance)
+
+	// But not below the overdraft limit
+	_, err = store.AdjustTx(context.Background(), AdjustTxParams{
+		AccountID: account.ID,
+		Amount:    -1,
+		Reason:    "fee",
+		Operator:  "ops",
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account := createRandomAccount(t)
+
+	if account.Balance != 0 {
+		_, err := store.CloseAccountTx(context.Background(), account.ID)
+		require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+		_, err = store.WithdrawTx(context.Background(), WithdrawTxParams{
+			AccountID:   account.ID,
+			Amount:      account.Balance,
+			Destination: "iban:DE89370400440532013000",
+		})
+		require.NoError(t, err)
+	}
+
+	closed, err := store.CloseAccountTx(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.AccountClosed, closed.Status)
+
+	// Closed accounts don't take money anymore, not even from operators
+	_, err = store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    10,
+		Source:    "card:4242",
+	})
+	require.ErrorIs(t, err, ErrAccountInactive)
+
+	_, err = store.AdjustTx(context.Background(), AdjustTxParams{
+		AccountID: account.ID,
+		Amount:    10,
+		Reason:    "goodwill",
+		Operator:  "ops",
+	})
+	require.ErrorIs(t, err, ErrAccountInactive)
+}
+
+func TestSetAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account := createRandomAccount(t)
+
+	frozen, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		From:      util.AccountActive,
+		To:        util.AccountFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.AccountFrozen, frozen.Status)
+
+	// The status is only changed from the one expected, e.g. not after someone else froze the account
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		From:      util.AccountActive,
+		To:        util.AccountFrozen,
+	})
+	require.ErrorIs(t, err, ErrAccountStatus)
+
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: 0,
+		From:      util.AccountActive,
+		To:        util.AccountFrozen,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxInactiveAccount(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	_, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account2.ID,
+		Status: util.AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	// Frozen accounts can neither send nor receive money
+	for _, arg := range []TransferTxParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 1},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 1},
+	} {
+		_, err = store.TransferTx(context.Background(), arg)
+		require.ErrorIs(t, err, ErrAccountInactive)
+	}
+}
@@ -0,0 +1,118 @@
+package db
+
+import "context"
+
+type DepositTxParams struct {
+	AccountID int64  `json:"account_id"`
+	Amount    int64  `json:"amount"`
+	Source    string `json:"source"`
+}
+
+type DepositTxResult struct {
+	Deposit Deposit `json:"deposit"`
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx brings money from an external source into an account.
+// It fails with ErrAccountInactive unless the account is active.
+func (store *SQLStore) DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error) {
+	var result DepositTxResult
+
+	err := store.execTx(ctx, "DepositTx", nil, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		err = checkActive(account)
+		if err != nil {
+			return err
+		}
+
+		result.Deposit, err = q.CreateDeposit(ctx, CreateDepositParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			Source:    arg.Source,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount,
+		})
+		return err
+	})
+	return result, err
+}
+
+type WithdrawTxParams struct {
+	AccountID   int64  `json:"account_id"`
+	Amount      int64  `json:"amount"`
+	Destination string `json:"destination"`
+}
+
+type WithdrawTxResult struct {
+	Withdrawal Withdrawal `json:"withdrawal"`
+	Account    Account    `json:"account"`
+	Entry      Entry      `json:"entry"`
+}
+
+// WithdrawTx takes money out of an account to an external destination.
+// It fails with ErrInsufficientFunds if the account doesn't hold enough money, overdraft included,
+// and with ErrAccountInactive unless the account is active.
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg WithdrawTxParams) (WithdrawTxResult, error) {
+	var result WithdrawTxResult
+
+	err := store.execTx(ctx, "WithdrawTx", nil, func(q *Queries) error {
+		// Lock the account, so concurrent withdrawals can't both pass the balance check
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		err = checkActive(account)
+		if err != nil {
+			return err
+		}
+
+		err = checkFunds(account, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Withdrawal, err = q.CreateWithdrawal(ctx, CreateWithdrawalParams{
+			AccountID:   arg.AccountID,
+			Amount:      arg.Amount,
+			Destination: arg.Destination,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    -arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: -arg.Amount,
+		})
+		return err
+	})
+	return result, asInsufficientFunds(err)
+}
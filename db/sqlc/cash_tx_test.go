@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account := createRandomAccount(t)
+	amount := int64(50)
+
+	result, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+		Source:    "card:4242",
+	})
+	require.NoError(t, err)
+
+	require.NotZero(t, result.Deposit.ID)
+	require.Equal(t, account.ID, result.Deposit.AccountID)
+	require.Equal(t, amount, result.Deposit.Amount)
+	require.Equal(t, "card:4242", result.Deposit.Source)
+
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, amount, result.Entry.Amount)
+	_, err = store.GetEntry(context.Background(), result.Entry.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, account.Balance+amount, result.Account.Balance)
+}
+
+func TestWithdrawTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	// Withdrawals must be positive, so the account needs some money to withdraw all of it
+	account := createRandomAccount(t)
+	for account.Balance == 0 {
+		account = createRandomAccount(t)
+	}
+
+	result, err := store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID:   account.ID,
+		Amount:      account.Balance,
+		Destination: "iban:DE89370400440532013000",
+	})
+	require.NoError(t, err)
+
+	require.NotZero(t, result.Withdrawal.ID)
+	require.Equal(t, account.ID, result.Withdrawal.AccountID)
+	require.Equal(t, account.Balance, result.Withdrawal.Amount)
+
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, -account.Balance, result.Entry.Amount)
+
+	require.Zero(t, result.Account.Balance)
+
+	// The account is empty now, so it can't be overdrawn
+	_, err = store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID:   account.ID,
+		Amount:      1,
+		Destination: "iban:DE89370400440532013000",
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount.Balance)
+}
@@ -0,0 +1,55 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: deposit.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createDeposit = `-- name: CreateDeposit :one
+insert into deposits (account_id, amount, source)
+values ($1, $2, $3)
+RETURNING id, account_id, amount, source, created_at
+`
+
+type CreateDepositParams struct {
+	AccountID int64  `json:"account_id"`
+	Amount    int64  `json:"amount"`
+	Source    string `json:"source"`
+}
+
+func (q *Queries) CreateDeposit(ctx context.Context, arg CreateDepositParams) (Deposit, error) {
+	row := q.db.QueryRowContext(ctx, createDeposit, arg.AccountID, arg.Amount, arg.Source)
+	var i Deposit
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.Source,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDeposit = `-- name: GetDeposit :one
+select id, account_id, amount, source, created_at
+from deposits
+where id = $1
+limit 1
+`
+
+func (q *Queries) GetDeposit(ctx context.Context, id int64) (Deposit, error) {
+	row := q.db.QueryRowContext(ctx, getDeposit, id)
+	var i Deposit
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.Source,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createEntry = `-- name: CreateEntry :one
@@ -51,6 +52,77 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 	return i, err
 }
 
+const listAccountEntries = `-- name: ListAccountEntries :many
+select id, account_id, amount, created_at
+from entries
+where account_id = $1
+  and ($2::timestamptz is null or created_at >= $2)
+  and ($3::timestamptz is null or created_at < $3)
+  and (
+    $4::text is null
+    or ($4 = 'incoming' and amount > 0)
+    or ($4 = 'outgoing' and amount < 0)
+  )
+  and ($5::bigint is null or abs(amount) >= $5)
+  and ($6::bigint is null or abs(amount) <= $6)
+  and (
+    $7::timestamptz is null
+    or (created_at, id) < ($7, $8::bigint)
+  )
+order by created_at desc, id desc
+limit $9
+`
+
+type ListAccountEntriesParams struct {
+	AccountID       int64          `json:"account_id"`
+	StartTime       sql.NullTime   `json:"start_time"`
+	EndTime         sql.NullTime   `json:"end_time"`
+	Direction       sql.NullString `json:"direction"`
+	MinAmount       sql.NullInt64  `json:"min_amount"`
+	MaxAmount       sql.NullInt64  `json:"max_amount"`
+	BeforeCreatedAt sql.NullTime   `json:"before_created_at"`
+	BeforeID        sql.NullInt64  `json:"before_id"`
+	Limit           int32          `json:"limit"`
+}
+
+func (q *Queries) ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntries,
+		arg.AccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Direction,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
 select id, account_id, amount, created_at
 from entries
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createEntryWithAmount(t *testing.T, account Account, amount int64) Entry {
+	entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+	return entry
+}
+
+func TestListAccountEntries(t *testing.T) {
+	account := createRandomAccount(t)
+
+	deposit := createEntryWithAmount(t, account, 100)
+	withdrawal := createEntryWithAmount(t, account, -30)
+	small := createEntryWithAmount(t, account, 5)
+
+	arg := ListAccountEntriesParams{
+		AccountID: account.ID,
+		Limit:     10,
+	}
+
+	// Newest first without filters
+	entries, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Entry{small, withdrawal, deposit}, entries)
+
+	arg.Direction = sql.NullString{String: "outgoing", Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Entry{withdrawal}, entries)
+
+	// Amount ranges apply to the absolute amount
+	arg.Direction = sql.NullString{}
+	arg.MinAmount = sql.NullInt64{Int64: 10, Valid: true}
+	arg.MaxAmount = sql.NullInt64{Int64: 50, Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Entry{withdrawal}, entries)
+
+	// Pages continue right before the last entry of the previous page
+	arg.MinAmount = sql.NullInt64{}
+	arg.MaxAmount = sql.NullInt64{}
+	arg.BeforeCreatedAt = sql.NullTime{Time: small.CreatedAt, Valid: true}
+	arg.BeforeID = sql.NullInt64{Int64: small.ID, Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Entry{withdrawal, deposit}, entries)
+
+	arg.BeforeCreatedAt = sql.NullTime{}
+	arg.BeforeID = sql.NullInt64{}
+	arg.StartTime = sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
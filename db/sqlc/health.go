@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrNoMigration is returned when the db has never been migrated
+var ErrNoMigration = errors.New("no migration applied")
+
+// Ping checks the db can be reached
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// MigrationVersion reads the version golang-migrate recorded. Dirty means the last migration failed halfway.
+func (store *SQLStore) MigrationVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	row := store.db.QueryRowContext(ctx, "select version, dirty from schema_migrations limit 1")
+	err = row.Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNoMigration
+	}
+	return
+}
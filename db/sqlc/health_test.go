@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kvnyijia/bank-app/db/migration"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	require.NoError(t, store.Ping(context.Background()))
+}
+
+func TestMigrationVersion(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	version, dirty, err := store.MigrationVersion(context.Background())
+	require.NoError(t, err)
+	require.False(t, dirty)
+
+	latest, err := migration.LatestVersion()
+	require.NoError(t, err)
+	require.Equal(t, latest, version)
+}
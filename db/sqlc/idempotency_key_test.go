@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+
+	arg := CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         util.RandomString(16),
+		RequestHash: util.RandomString(64),
+	}
+
+	key1, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, key1.Username)
+	require.Equal(t, arg.Key, key1.Key)
+	require.Equal(t, arg.RequestHash, key1.RequestHash)
+	require.Zero(t, key1.ResponseStatus)
+	require.Empty(t, key1.ResponseBody)
+
+	// The same key can't be taken twice
+	_, err = testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	key2, err := testQueries.UpdateIdempotencyKeyResponse(context.Background(), UpdateIdempotencyKeyResponseParams{
+		Username:       arg.Username,
+		Key:            arg.Key,
+		ResponseStatus: http.StatusOK,
+		ResponseBody:   []byte(`{}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(http.StatusOK), key2.ResponseStatus)
+	require.Equal(t, []byte(`{}`), key2.ResponseBody)
+
+	key3, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key2, key3)
+
+	err = testQueries.DeleteIdempotencyKey(context.Background(), DeleteIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateIdempotencyKeyReclaims(t *testing.T) {
+	user := createRandomUser(t)
+
+	arg := CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         util.RandomString(16),
+		RequestHash: util.RandomString(64),
+	}
+	key1, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+
+	// Another request can't take over a key in progress, however long it has been
+	abandoned := arg
+	abandoned.RequestHash = util.RandomString(64)
+	abandoned.AbandonedBefore = time.Now().Add(time.Minute)
+	_, err = testQueries.CreateIdempotencyKey(context.Background(), abandoned)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// A retry of the same request can, once it's in progress for longer than the abandon timeout
+	arg.AbandonedBefore = time.Now().Add(time.Minute)
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, key2.RequestHash)
+	require.True(t, key2.CreatedAt.After(key1.CreatedAt))
+
+	_, err = testQueries.UpdateIdempotencyKeyResponse(context.Background(), UpdateIdempotencyKeyResponseParams{
+		Username:       arg.Username,
+		Key:            arg.Key,
+		ResponseStatus: http.StatusOK,
+		ResponseBody:   []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	// A completed key is only free again once it expired
+	_, err = testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	expired := abandoned
+	expired.ExpiredBefore = time.Now().Add(time.Minute)
+	key3, err := testQueries.CreateIdempotencyKey(context.Background(), expired)
+	require.NoError(t, err)
+	require.Equal(t, expired.RequestHash, key3.RequestHash)
+	require.Zero(t, key3.ResponseStatus)
+	require.Empty(t, key3.ResponseBody)
+
+	deleted, err := testQueries.DeleteExpiredIdempotencyKeys(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Positive(t, deleted)
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/kvnyijia/bank-app/util"
 	_ "github.com/lib/pq"
@@ -13,6 +14,9 @@ import (
 var testQueries *Queries
 var testDB *sql.DB
 
+// Concurrent txs in tests conflict a lot, so they're retried quickly
+var testRetryPolicy = RetryPolicy{MaxRetries: 5, Backoff: time.Millisecond}
+
 func TestMain(m *testing.M) {
 	config, err := util.LoadConfig("../..")
 	if err != nil {
@@ -5,7 +5,10 @@
 package db
 
 import (
+	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +17,31 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far the balance may go below zero
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// only active accounts move money
+	Status string `json:"status"`
+}
+
+type Adjustment struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// positive to credit the account, negative to debit it
+	Amount int64 `json:"amount"`
+	// why an operator changed the balance by hand
+	Reason    string    `json:"reason"`
+	Operator  string    `json:"operator"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Deposit struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// must be positive
+	Amount int64 `json:"amount"`
+	// external reference of where the money comes from
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -24,13 +52,66 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type IdempotencyKey struct {
+	// empty for requests without authentication
+	Username    string `json:"username"`
+	Key         string `json:"key"`
+	RequestHash string `json:"request_hash"`
+	// 0 while the request is in progress
+	ResponseStatus int32     `json:"response_status"`
+	ResponseBody   []byte    `json:"response_body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PasswordResetEmail struct {
+	ID int64 `json:"id"`
+	// as asked for, so not every row belongs to a user
+	Email        string    `json:"email"`
+	SendAttempts int32     `json:"send_attempts"`
+	NextSendAt   time.Time `json:"next_send_at"`
+	// also set when no user has the email, since there is nothing to send then
+	SentAt    sql.NullTime `json:"sent_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	// sha256 of the token sent to the user, which is never stored
+	TokenHash string       `json:"token_hash"`
+	Username  string       `json:"username"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	// sha256 of the recovery code shown to the user, which is never stored
+	CodeHash  string       `json:"code_hash"`
+	Username  string       `json:"username"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Session struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// must be positive
+	// must be positive, in the currency of the from account
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// must be positive, in the currency of the to account
+	ToAmount int64 `json:"to_amount"`
+	// units of the to currency per unit of the from currency
+	ExchangeRate string `json:"exchange_rate"`
 }
 
 type User struct {
@@ -40,4 +121,42 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+	// failed logins in a row, reset by a successful one
+	FailedLoginAttempts int32 `json:"failed_login_attempts"`
+	// logins are refused until then
+	LockedUntil     time.Time `json:"locked_until"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+	// TOTP secret sealed with the TOTP encryption key, set on enrollment & only used once enabled
+	TotpSecret  string `json:"totp_secret"`
+	TotpEnabled bool   `json:"totp_enabled"`
+	// time step of the last TOTP code used, so no code works twice
+	TotpLastStep int64 `json:"totp_last_step"`
+	// issue time of the last MFA challenge exchanged for a login, so no challenge works twice
+	MfaChallengeUsedAt time.Time `json:"mfa_challenge_used_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// sha256 of the code in the email, set once the email is about to be sent
+	SecretCodeHash string `json:"secret_code_hash"`
+	IsUsed         bool   `json:"is_used"`
+	SendAttempts   int32  `json:"send_attempts"`
+	// the email worker leaves the email alone until then, so a failed send is retried later
+	NextSendAt time.Time    `json:"next_send_at"`
+	SentAt     sql.NullTime `json:"sent_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiredAt  time.Time    `json:"expired_at"`
+}
+
+type Withdrawal struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// must be positive
+	Amount int64 `json:"amount"`
+	// external reference of where the money goes to
+	Destination string    `json:"destination"`
+	CreatedAt   time.Time `json:"created_at"`
 }
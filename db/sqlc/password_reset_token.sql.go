@@ -0,0 +1,138 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: password_reset_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const claimPasswordResetEmail = `-- name: ClaimPasswordResetEmail :one
+UPDATE password_reset_emails
+SET
+  send_attempts = send_attempts + 1,
+  next_send_at = now() + make_interval(secs => $1::float8 * power(2, LEAST(send_attempts, 30)))
+WHERE id = (
+  SELECT id FROM password_reset_emails
+  WHERE sent_at IS NULL
+    AND send_attempts < $2::int
+    AND next_send_at <= now()
+  ORDER BY next_send_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, email, send_attempts, next_send_at, sent_at, created_at
+`
+
+type ClaimPasswordResetEmailParams struct {
+	RetrySeconds float64 `json:"retry_seconds"`
+	MaxAttempts  int32   `json:"max_attempts"`
+}
+
+// Takes the next email due to be sent. Like ClaimVerifyEmail, the email isn't due again until retry_seconds later,
+// twice as long after every attempt, so other workers leave it alone while it's sent & it's retried if the send fails.
+func (q *Queries) ClaimPasswordResetEmail(ctx context.Context, arg ClaimPasswordResetEmailParams) (PasswordResetEmail, error) {
+	row := q.db.QueryRowContext(ctx, claimPasswordResetEmail, arg.RetrySeconds, arg.MaxAttempts)
+	var i PasswordResetEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.SendAttempts,
+		&i.NextSendAt,
+		&i.SentAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPasswordResetEmail = `-- name: CreatePasswordResetEmail :exec
+INSERT INTO password_reset_emails (
+  email
+) VALUES (
+  $1
+)
+`
+
+func (q *Queries) CreatePasswordResetEmail(ctx context.Context, email string) error {
+	_, err := q.db.ExecContext(ctx, createPasswordResetEmail, email)
+	return err
+}
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+  token_hash,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING token_hash, username, expires_at, used_at, created_at
+`
+
+type CreatePasswordResetTokenParams struct {
+	TokenHash string    `json:"token_hash"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordResetToken, arg.TokenHash, arg.Username, arg.ExpiresAt)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const invalidatePasswordResetTokens = `-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1
+  AND used_at IS NULL
+`
+
+// Marks every token of the user which isn't used yet as used, so none of them works once the password changed
+func (q *Queries) InvalidatePasswordResetTokens(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, invalidatePasswordResetTokens, username)
+	return err
+}
+
+const markPasswordResetEmailSent = `-- name: MarkPasswordResetEmailSent :exec
+UPDATE password_reset_emails
+SET sent_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkPasswordResetEmailSent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markPasswordResetEmailSent, id)
+	return err
+}
+
+const usePasswordResetToken = `-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING token_hash, username, expires_at, used_at, created_at
+`
+
+// Marks the token as used, as long as it's neither used nor expired yet, so it works only once
+func (q *Queries) UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, usePasswordResetToken, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
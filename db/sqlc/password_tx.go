@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrInvalidResetToken is returned when a password reset token is unknown, expired or already used
+var ErrInvalidResetToken = errors.New("password reset token is invalid or expired")
+
+type ResetPasswordTxParams struct {
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+// ResetPasswordTx uses up a password reset token and sets the new password of its user, which invalidates the other tokens.
+// Proving access to the email also unlocks the user, and every session of the user is blocked.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, "ResetPasswordTx", nil, func(q *Queries) error {
+		token, err := q.UsePasswordResetToken(ctx, arg.TokenHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+
+		result, _, err = resetPassword(ctx, q, UpdateUserPasswordParams{
+			Username:       token.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		return err
+	})
+	return result, err
+}
+
+type SetPasswordTxResult struct {
+	User            User  `json:"user"`
+	BlockedSessions int64 `json:"blocked_sessions"`
+}
+
+// SetPasswordTx sets a new password for a user who can't themselves, e.g. by an operator.
+// Like a reset thru email, it unlocks the user & blocks every session of the user, all or nothing.
+func (store *SQLStore) SetPasswordTx(ctx context.Context, arg UpdateUserPasswordParams) (SetPasswordTxResult, error) {
+	var result SetPasswordTxResult
+
+	err := store.execTx(ctx, "SetPasswordTx", nil, func(q *Queries) error {
+		var err error
+		result.User, result.BlockedSessions, err = resetPassword(ctx, q, arg)
+		return err
+	})
+	return result, err
+}
+
+// ChangePasswordTx sets the new password of a user, and makes the password reset tokens the user didn't use stop working,
+// since they were asked for to replace the old password
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, "ChangePasswordTx", nil, func(q *Queries) error {
+		var err error
+		result, err = changePassword(ctx, q, arg)
+		return err
+	})
+	return result, err
+}
+
+func changePassword(ctx context.Context, q *Queries, arg UpdateUserPasswordParams) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, arg)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = q.InvalidatePasswordResetTokens(ctx, arg.Username)
+	return user, err
+}
+
+// resetPassword changes the password of a user who may have lost it to someone else, so the user is unlocked
+// & signed out everywhere. It returns how many sessions were blocked.
+func resetPassword(ctx context.Context, q *Queries, arg UpdateUserPasswordParams) (User, int64, error) {
+	user, err := changePassword(ctx, q, arg)
+	if err != nil {
+		return User{}, 0, err
+	}
+
+	if err = q.ResetFailedLogins(ctx, arg.Username); err != nil {
+		return User{}, 0, err
+	}
+
+	blocked, err := q.BlockUserSessions(ctx, arg.Username)
+	if err != nil {
+		return User{}, 0, err
+	}
+	return user, blocked, nil
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomResetToken(t *testing.T, user User, expiresAt time.Time) (string, PasswordResetToken) {
+	token, err := util.NewSecureToken()
+	require.NoError(t, err)
+
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		TokenHash: util.HashSecureToken(token),
+		Username:  user.Username,
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, resetToken.Username)
+	require.False(t, resetToken.UsedAt.Valid)
+	return token, resetToken
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	session := createRandomSession(t)
+	user, err := testQueries.RecordFailedLogin(context.Background(), RecordFailedLoginParams{
+		Username:          session.Username,
+		MaxAttempts:       1,
+		LockoutSeconds:    60,
+		MaxLockoutSeconds: 60,
+	})
+	require.NoError(t, err)
+	require.True(t, user.LockedUntil.After(time.Now()))
+
+	token, _ := createRandomResetToken(t, user, time.Now().Add(time.Hour))
+	otherToken, _ := createRandomResetToken(t, user, time.Now().Add(time.Hour))
+	arg := ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: "new-hashed-password",
+	}
+
+	updated, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.HashedPassword, updated.HashedPassword)
+	require.WithinDuration(t, time.Now(), updated.PasswordChangedAt, 5*time.Second)
+
+	// The user is unlocked & signed out everywhere
+	require.Zero(t, updated.FailedLoginAttempts)
+	require.True(t, updated.LockedUntil.Before(time.Now()))
+	blocked, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	// Tokens work only once, and the other tokens of the user stop working too
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+
+	arg.TokenHash = util.HashSecureToken(otherToken)
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	user := createRandomUser(t)
+	token, _ := createRandomResetToken(t, user, time.Now().Add(time.Hour))
+
+	updated, err := store.ChangePasswordTx(context.Background(), UpdateUserPasswordParams{
+		Username:       user.Username,
+		HashedPassword: "new-hashed-password",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hashed-password", updated.HashedPassword)
+	require.WithinDuration(t, time.Now(), updated.PasswordChangedAt, 5*time.Second)
+
+	// A reset link asked for before can't set the password any more
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: "other-hashed-password",
+	})
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+
+	_, err = store.ChangePasswordTx(context.Background(), UpdateUserPasswordParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "new-hashed-password",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetPasswordTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	session := createRandomSession(t)
+	_, err := testQueries.RecordFailedLogin(context.Background(), RecordFailedLoginParams{
+		Username:          session.Username,
+		MaxAttempts:       1,
+		LockoutSeconds:    60,
+		MaxLockoutSeconds: 60,
+	})
+	require.NoError(t, err)
+
+	result, err := store.SetPasswordTx(context.Background(), UpdateUserPasswordParams{
+		Username:       session.Username,
+		HashedPassword: "new-hashed-password",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hashed-password", result.User.HashedPassword)
+	require.Equal(t, int64(1), result.BlockedSessions)
+
+	// The user is unlocked & signed out everywhere
+	user, err := testQueries.GetUser(context.Background(), session.Username)
+	require.NoError(t, err)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.True(t, user.LockedUntil.Before(time.Now()))
+	blocked, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	_, err = store.SetPasswordTx(context.Background(), UpdateUserPasswordParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "new-hashed-password",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestResetPasswordTxInvalidToken(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	user := createRandomUser(t)
+
+	expired, _ := createRandomResetToken(t, user, time.Now().Add(-time.Minute))
+	unknown, err := util.NewSecureToken()
+	require.NoError(t, err)
+
+	for _, token := range []string{expired, unknown} {
+		_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+			TokenHash:      util.HashSecureToken(token),
+			HashedPassword: "new-hashed-password",
+		})
+		require.ErrorIs(t, err, ErrInvalidResetToken)
+	}
+
+	// The password stays as it was
+	unchanged, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, unchanged.HashedPassword)
+}
+
+func TestClaimPasswordResetEmail(t *testing.T) {
+	email := util.RandomEmail()
+	err := testQueries.CreatePasswordResetEmail(context.Background(), email)
+	require.NoError(t, err)
+
+	// Other tests may have queued emails too
+	var resetEmail PasswordResetEmail
+	for resetEmail.Email != email {
+		resetEmail, err = testQueries.ClaimPasswordResetEmail(context.Background(), ClaimPasswordResetEmailParams{
+			RetrySeconds: 60,
+			MaxAttempts:  3,
+		})
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), resetEmail.SendAttempts)
+	require.WithinDuration(t, time.Now().Add(time.Minute), resetEmail.NextSendAt, 5*time.Second)
+
+	err = testQueries.MarkPasswordResetEmailSent(context.Background(), resetEmail.ID)
+	require.NoError(t, err)
+
+	var sentAt sql.NullTime
+	err = testDB.QueryRowContext(context.Background(), "SELECT sent_at FROM password_reset_emails WHERE id = $1", resetEmail.ID).Scan(&sentAt)
+	require.NoError(t, err)
+	require.True(t, sentAt.Valid)
+}
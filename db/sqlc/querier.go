@@ -6,24 +6,92 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	// Takes the next email due to be sent. Like ClaimVerifyEmail, the email isn't due again until retry_seconds later,
+	// twice as long after every attempt, so other workers leave it alone while it's sent & it's retried if the send fails.
+	ClaimPasswordResetEmail(ctx context.Context, arg ClaimPasswordResetEmailParams) (PasswordResetEmail, error)
+	// Takes the next email due to be sent & sets its code. The email isn't due again until retry_seconds later,
+	// twice as long after every attempt, so other workers leave it alone while it's sent & it's retried if the send fails.
+	ClaimVerifyEmail(ctx context.Context, arg ClaimVerifyEmailParams) (VerifyEmail, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAdjustment(ctx context.Context, arg CreateAdjustmentParams) (Adjustment, error)
+	CreateDeposit(ctx context.Context, arg CreateDepositParams) (Deposit, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	// Takes the key, unless another request holds it. A key is free again once it expired, or once the request holding it
+	// was abandoned in progress, e.g. the server crashed, and the same request is retried.
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreatePasswordResetEmail(ctx context.Context, email string) error
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWithdrawal(ctx context.Context, arg CreateWithdrawalParams) (Withdrawal, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, createdAt time.Time) (int64, error)
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DisableTOTP(ctx context.Context, username string) (User, error)
+	// The step of the code which confirmed the enrollment counts as used
+	EnableTOTP(ctx context.Context, arg EnableTOTPParams) (User, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAdjustment(ctx context.Context, id int64) (Adjustment, error)
+	// The session along with when its user last changed their password, which makes tokens issued before then invalid
+	GetAuthSession(ctx context.Context, id uuid.UUID) (GetAuthSessionRow, error)
+	GetDeposit(ctx context.Context, id int64) (Deposit, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetWithdrawal(ctx context.Context, id int64) (Withdrawal, error)
+	// Marks every token of the user which isn't used yet as used, so none of them works once the password changed
+	InvalidatePasswordResetTokens(ctx context.Context, username string) error
+	ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error)
+	// Newest first. Amounts are compared in the currency of the given account.
+	ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error)
+	// Oldest first, starting right after the (created_at, id) of the last row of the previous page
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListSessions(ctx context.Context, username string) ([]Session, error)
 	ListTarnsfer(ctx context.Context, arg ListTarnsferParams) ([]Transfer, error)
+	MarkPasswordResetEmailSent(ctx context.Context, id int64) error
+	MarkVerifyEmailSent(ctx context.Context, id int64) error
+	// From max_attempts failed logins in a row on, every failed one locks the user for twice as long as the one before,
+	// starting from lockout_seconds & up to max_lockout_seconds
+	RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error)
+	ResetFailedLogins(ctx context.Context, username string) error
+	// Starts over an enrollment, which is only possible while TOTP is not enabled yet
+	SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) (User, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
 	UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) (IdempotencyKey, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	// Only succeeds for a challenge issued later than the last one used, so a challenge can't be replayed
+	UseMFAChallenge(ctx context.Context, arg UseMFAChallengeParams) (User, error)
+	// Marks the token as used, as long as it's neither used nor expired yet, so it works only once
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	// Marks the code as used, as long as it belongs to the user & isn't used yet, so it works only once
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	// Only succeeds for a step later than the last one used, so a code can't be replayed
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error)
+	// Marks the code as used, as long as it's right & neither used nor expired yet
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
+	// The email is only verified if the user still has the email the code was sent to
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
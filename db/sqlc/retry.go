@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoffs never grow beyond this, however many retries are allowed
+const maxRetryBackoff = time.Second
+
+// RetryPolicy tells how often a tx aborted by the db because of a concurrent one, i.e. a deadlock or
+// a serialization failure, is run again. Such a tx did nothing, so running it again is always safe.
+type RetryPolicy struct {
+	MaxRetries int           // 0 turns retries off
+	Backoff    time.Duration // Wait before the first retry, which doubles with every retry after it
+}
+
+// backoff is the wait before the retry following the attempt. Half of it is random,
+// so txs which conflicted with each other don't all retry at the same time & conflict again.
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := policy.Backoff
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// sleep waits for the duration unless the ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
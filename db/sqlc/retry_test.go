@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, Backoff: 100 * time.Millisecond}
+
+	for i := 0; i < 20; i++ {
+		backoff := policy.backoff(1)
+		require.GreaterOrEqual(t, backoff, 50*time.Millisecond)
+		require.LessOrEqual(t, backoff, 100*time.Millisecond)
+
+		backoff = policy.backoff(3)
+		require.GreaterOrEqual(t, backoff, 200*time.Millisecond)
+		require.LessOrEqual(t, backoff, 400*time.Millisecond)
+
+		backoff = policy.backoff(10)
+		require.GreaterOrEqual(t, backoff, maxRetryBackoff/2)
+		require.LessOrEqual(t, backoff, maxRetryBackoff)
+	}
+
+	require.Zero(t, RetryPolicy{}.backoff(1))
+}
+
+func TestExecTxRetry(t *testing.T) {
+	deadlock := &pq.Error{Code: "40P01"}
+	serializationFailure := &pq.Error{Code: "40001"}
+
+	testCases := []struct {
+		name          string
+		errs          []error // Returned by the attempts in turn, the last one is returned by any later attempt
+		checkAttempts func(t *testing.T, attempts int, err error)
+	}{
+		{
+			name: "RetriedUntilSuccess",
+			errs: []error{deadlock, serializationFailure, nil},
+			checkAttempts: func(t *testing.T, attempts int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 3, attempts)
+			},
+		},
+		{
+			name: "GiveUp",
+			errs: []error{deadlock},
+			checkAttempts: func(t *testing.T, attempts int, err error) {
+				require.ErrorIs(t, err, deadlock)
+				require.Equal(t, testRetryPolicy.MaxRetries+1, attempts)
+			},
+		},
+		{
+			name: "NotRetryable",
+			errs: []error{ErrInsufficientFunds},
+			checkAttempts: func(t *testing.T, attempts int, err error) {
+				require.ErrorIs(t, err, ErrInsufficientFunds)
+				require.Equal(t, 1, attempts)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore(testDB, zerolog.Nop(), testRetryPolicy).(*SQLStore)
+
+			attempts := 0
+			err := store.execTx(context.Background(), "TestTx", nil, func(q *Queries) error {
+				i := attempts
+				if i >= len(tc.errs) {
+					i = len(tc.errs) - 1
+				}
+				attempts++
+				return tc.errs[i]
+			})
+			tc.checkAttempts(t, attempts, err)
+		})
+	}
+}
+
+func TestExecTxRetryStopsWithContext(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), RetryPolicy{MaxRetries: 5, Backoff: time.Minute}).(*SQLStore)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := store.execTx(ctx, "TestTx", nil, func(q *Queries) error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+
+	var pqErr *pq.Error
+	require.True(t, errors.As(err, &pqErr))
+	require.Equal(t, 1, attempts)
+}
+
+func TestExecTxReadOnly(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy).(*SQLStore)
+	account := createRandomAccount(t)
+
+	err := store.execTx(context.Background(), "TestTx", &sql.TxOptions{ReadOnly: true}, func(q *Queries) error {
+		_, err := q.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+			ID:     account.ID,
+			Amount: 10,
+		})
+		return err
+	})
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "read_only_sql_transaction", pqErr.Code.Name())
+}
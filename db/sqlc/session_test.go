@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t)
+
+	arg := CreateSessionParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		UserAgent: "test-agent",
+		ClientIp:  "127.0.0.1",
+		IsBlocked: false,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	session1 := createRandomSession(t)
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, session2)
+
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.Username, session2.Username)
+	require.Equal(t, session1.IsBlocked, session2.IsBlocked)
+	require.WithinDuration(t, session1.ExpiresAt, session2.ExpiresAt, time.Second)
+	require.WithinDuration(t, session1.CreatedAt, session2.CreatedAt, time.Second)
+}
+
+func TestGetAuthSession(t *testing.T) {
+	session := createRandomSession(t)
+
+	row, err := testQueries.GetAuthSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, row.Session.ID)
+	require.Equal(t, session.Username, row.Session.Username)
+	require.True(t, row.PasswordChangedAt.IsZero())
+
+	user, err := testQueries.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:       session.Username,
+		HashedPassword: "hashed",
+	})
+	require.NoError(t, err)
+
+	row, err = testQueries.GetAuthSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, user.PasswordChangedAt, row.PasswordChangedAt, time.Second)
+}
+
+func TestListSessions(t *testing.T) {
+	session := createRandomSession(t)
+
+	sessions, err := testQueries.ListSessions(context.Background(), session.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session.ID, sessions[0].ID)
+}
+
+func TestBlockSession(t *testing.T) {
+	session1 := createRandomSession(t)
+
+	_, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: util.RandomOwner(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	session2, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: session1.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, session2.IsBlocked)
+
+	sessions, err := testQueries.ListSessions(context.Background(), session1.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	session1 := createRandomSession(t)
+
+	revoked, err := testQueries.BlockUserSessions(context.Background(), session1.Username)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revoked)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.True(t, session2.IsBlocked)
+}
@@ -3,43 +3,195 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error)
+	WithdrawTx(ctx context.Context, arg WithdrawTxParams) (WithdrawTxResult, error)
+	AdjustTx(ctx context.Context, arg AdjustTxParams) (AdjustTxResult, error)
+	CloseAccountTx(ctx context.Context, accountID int64) (Account, error)
+	SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (Account, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (CreateUserTxResult, error)
+	CreateVerifiedUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	ChangePasswordTx(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	SetPasswordTx(ctx context.Context, arg UpdateUserPasswordParams) (SetPasswordTxResult, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error)
+	DisableTOTPTx(ctx context.Context, username string) (User, error)
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+// ErrInsufficientFunds is returned when a tx would take more money out of an account than it holds.
+// The actual error is an *InsufficientFundsError, so use errors.Is to check for it.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// balanceCheckConstraint keeps balances above their overdraft limit in the db, whatever code path updates them
+const balanceCheckConstraint = "accounts_balance_check"
+
+type InsufficientFundsError struct {
+	AccountID int64
+	Available int64 // Balance plus overdraft limit
+	Amount    int64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	if e.AccountID == 0 {
+		return ErrInsufficientFunds.Error()
+	}
+	return fmt.Sprintf("%s: account [%d] has %d available but %d is required", ErrInsufficientFunds, e.AccountID, e.Available, e.Amount)
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// checkFunds makes sure the locked account can give away the amount
+func checkFunds(account Account, amount int64) error {
+	available := account.Balance + account.OverdraftLimit
+	if available < amount {
+		return &InsufficientFundsError{
+			AccountID: account.ID,
+			Available: available,
+			Amount:    amount,
+		}
+	}
+	return nil
+}
+
+// asInsufficientFunds turns a violation of the balance check into ErrInsufficientFunds, and leaves other errors untouched
+func asInsufficientFunds(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "check_violation" && pqErr.Constraint == balanceCheckConstraint {
+		return &InsufficientFundsError{}
+	}
+	return err
+}
+
+// ErrAccountInactive is returned when a tx would move money in or out of a frozen or closed account.
+// The actual error is an *AccountInactiveError, so use errors.Is to check for it.
+var ErrAccountInactive = errors.New("account is not active")
+
+type AccountInactiveError struct {
+	AccountID int64
+	Status    string
+}
+
+func (e *AccountInactiveError) Error() string {
+	return fmt.Sprintf("%s: account [%d] is %s", ErrAccountInactive, e.AccountID, e.Status)
+}
+
+func (e *AccountInactiveError) Is(target error) bool {
+	return target == ErrAccountInactive
+}
+
+// checkActive makes sure the locked account can move money
+func checkActive(accounts ...Account) error {
+	for _, account := range accounts {
+		if account.Status != util.AccountActive {
+			return &AccountInactiveError{AccountID: account.ID, Status: account.Status}
+		}
+	}
+	return nil
 }
 
 // Provides all funcs to exec SQL queries & txs
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db          *sql.DB
+	logger      zerolog.Logger
+	retryPolicy RetryPolicy
 }
 
-func NewStore(db *sql.DB) Store {
+func NewStore(db *sql.DB, logger zerolog.Logger, retryPolicy RetryPolicy) Store {
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		db:          db,
+		Queries:     New(tracedDB{DBTX: db}),
+		logger:      logger,
+		retryPolicy: retryPolicy,
+	}
+}
+
+// loggerFor prefers the logger of the req the ctx belongs to, which knows its request ID
+func (store *SQLStore) loggerFor(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &store.logger
+}
+
+// execTx executes a function within a database tx, started with the options (nil for the defaults).
+// A tx aborted because of a concurrent one is run again from the start, as the retry policy of the store allows,
+// so fn must not have side effects outside of the tx.
+func (store *SQLStore) execTx(ctx context.Context, name string, opts *sql.TxOptions, fn func(*Queries) error) error {
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		err = store.execTxAttempt(ctx, name, opts, attempt, fn)
+
+		code := txConflictCode(err)
+		if code == "" || attempt > store.retryPolicy.MaxRetries {
+			break
+		}
+
+		metrics.TxRetries.WithLabelValues(name, code).Inc()
+		store.loggerFor(ctx).Debug().Err(err).Str("tx", name).Int("attempt", attempt).Msg("retry tx")
+
+		if sleep(ctx, store.retryPolicy.backoff(attempt)) != nil {
+			break
+		}
 	}
+
+	metrics.TxAttempts.WithLabelValues(name).Observe(float64(attempt))
+	return err
 }
 
-// execTx executes a function within a database tx
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// execTxAttempt runs the tx once. Every attempt gets a span named after the tx,
+// which holds a span for each step, i.e. begin, every query, and commit or rollback.
+func (store *SQLStore) execTxAttempt(ctx context.Context, name string, opts *sql.TxOptions, attempt int, fn func(*Queries) error) (err error) {
+	ctx, span := tracer().Start(ctx, "db."+name, trace.WithAttributes(attribute.Int("db.tx.attempt", attempt)))
+	defer func() { endSpan(span, err) }()
+
+	var tx *sql.Tx
+	err = traceStep(ctx, "db.Begin", func(ctx context.Context) (err error) {
+		tx, err = store.db.BeginTx(ctx, opts)
+		return
+	})
 	if err != nil {
 		return err
 	}
 
-	q := New(tx)
+	q := New(tracedDB{DBTX: tx, parent: span})
 	err = fn(q)
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		if rbErr := traceStep(ctx, "db.Rollback", func(context.Context) error { return tx.Rollback() }); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
 		}
 		return err
 	}
-	return tx.Commit()
+
+	return traceStep(ctx, "db.Commit", func(context.Context) error { return tx.Commit() })
+}
+
+// traceStep runs a step of a tx in its own span
+func traceStep(ctx context.Context, name string, step func(context.Context) error) error {
+	ctx, span := tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	err := step(ctx)
+	endSpan(span, err)
+	return err
 }
 
 type TransferTxParams struct {
@@ -48,6 +200,16 @@ type TransferTxParams struct {
 	Amount        int64 `json:"amount"`
 }
 
+// ExchangeTransferTxParams moves money between accounts of different currencies:
+// Amount is taken from the from account, and ToAmount is added to the to account
+type ExchangeTransferTxParams struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	ToAmount      int64  `json:"to_amount"`
+	ExchangeRate  string `json:"exchange_rate"`
+}
+
 type TransferTxResult struct {
 	Transfer    Transfer `json:"transfer"`
 	FromAccount Account  `json:"from_account"`
@@ -56,30 +218,63 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 }
 
-var txKey = struct{}{}
-
 // TransferTx performs a money transfer from one account to the other
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	return store.ExchangeTransferTx(ctx, ExchangeTransferTxParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.Amount,
+		ExchangeRate:  "1",
+	})
+}
+
+// ExchangeTransferTx performs a money transfer between accounts which may hold different currencies,
+// and records the exchange rate on the transfer
+func (store *SQLStore) ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, "ExchangeTransferTx", nil, func(q *Queries) error {
 		var err error
 
-		txName := ctx.Value(txKey)
+		logger := store.loggerFor(ctx).With().
+			Int64("from_account_id", arg.FromAccountID).
+			Int64("to_account_id", arg.ToAccountID).
+			Logger()
+
+		// Lock both accounts in the same order as the balance updates below to avoid deadlocks,
+		// then make sure both are active and the from account can afford the transfer
+		logger.Debug().Msg("lock accounts")
+		fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		err = checkActive(fromAccount, toAccount)
+		if err != nil {
+			return err
+		}
+
+		err = checkFunds(fromAccount, arg.Amount)
+		if err != nil {
+			return err
+		}
 
 		// Callback func
 		// Use `result` & `arg` from outside -> closure
-		fmt.Println(txName, "create transfer")
+		logger.Debug().Msg("create transfer")
 		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
 			FromAccountID: arg.FromAccountID,
 			ToAccountID:   arg.ToAccountID,
 			Amount:        arg.Amount,
+			ToAmount:      arg.ToAmount,
+			ExchangeRate:  arg.ExchangeRate,
 		})
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(txName, "create entry 1")
+		logger.Debug().Msg("create from entry")
 		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
 			AccountID: arg.FromAccountID,
 			Amount:    -arg.Amount,
@@ -88,26 +283,82 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 			return err
 		}
 
-		fmt.Println(txName, "create entry 1")
+		logger.Debug().Msg("create to entry")
 		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
 			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+			Amount:    arg.ToAmount,
 		})
 		if err != nil {
 			return err
 		}
 
 		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.ToAmount)
 		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.ToAmount, arg.FromAccountID, -arg.Amount)
 		}
 
-		return nil
+		return err
 	})
+
+	err = asInsufficientFunds(err)
+	observeTransfer(result, arg.Amount, err)
 	return result, err
 }
 
+// observeTransfer counts the transfer by its result, and records the amount of a created one
+func observeTransfer(result TransferTxResult, amount int64, err error) {
+	if err == nil {
+		metrics.Transfers.WithLabelValues(metrics.TransferCreated).Inc()
+		metrics.TransferAmount.WithLabelValues(result.FromAccount.Currency).Observe(float64(amount))
+		return
+	}
+
+	reason := metrics.TransferError
+	switch code := txConflictCode(err); {
+	case errors.Is(err, ErrInsufficientFunds):
+		reason = metrics.TransferInsufficientFunds
+	case errors.Is(err, ErrAccountInactive):
+		reason = metrics.TransferAccountInactive
+	case code != "":
+		reason = code
+		metrics.TransferTxConflicts.WithLabelValues(code).Inc()
+	}
+	metrics.Transfers.WithLabelValues(reason).Inc()
+}
+
+// txConflictCode is the name of the error code when the db aborted the tx because of a concurrent one,
+// i.e. a deadlock or a serialization failure, and is empty otherwise
+func txConflictCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch name := pqErr.Code.Name(); name {
+		case "deadlock_detected", "serialization_failure":
+			return name
+		}
+	}
+	return ""
+}
+
+// lockAccounts locks the two accounts in ascending ID order
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (fromAccount Account, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return
+		}
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return
+	}
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return
+}
+
 func addMoney(
 	ctx context.Context,
 	q *Queries,
@@ -3,29 +3,35 @@ package db
 import (
 	"context"
 	"fmt"
+	"os"
 	"testing"
 
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
-	store := NewStore(testDB) // testDB is the global var from main_test.go
-
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
-	fmt.Println(">> before:", account1.Balance, account2.Balance)
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy) // testDB is the global var from main_test.go
 
 	// Run n concurrent transfer tx
 	n := 5
 	amount := int64(10)
 
+	account1 := fundAccount(t, createRandomAccount(t), int64(n)*amount)
+	account2 := createRandomAccount(t)
+	fmt.Println(">> before:", account1.Balance, account2.Balance)
+
 	errs := make(chan error) // Using channel to connect Go routines
 	results := make(chan TransferTxResult)
 
 	for i := 0; i < n; i++ {
 		txName := fmt.Sprintf("tx %d", i+1)
 		go func() {
-			ctx := context.WithValue(context.Background(), txKey, txName)
+			// The tx logs its steps at debug level, tagged with the name of the tx
+			logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel).With().Str("tx", txName).Logger()
+			ctx := logger.WithContext(context.Background())
 			result, err := store.TransferTx(ctx, TransferTxParams{
 				FromAccountID: account1.ID,
 				ToAccountID:   account2.ID,
@@ -112,16 +118,17 @@ func TestTransferTx(t *testing.T) {
 }
 
 func TestTransferTxDeadlock(t *testing.T) {
-	store := NewStore(testDB) // testDB is the global var from main_test.go
-
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
-	fmt.Println(">> before:", account1.Balance, account2.Balance)
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy) // testDB is the global var from main_test.go
 
 	// Run n concurrent transfer tx
 	n := 10
 	amount := int64(10)
 
+	// Either account may send all its transfers before receiving any
+	account1 := fundAccount(t, createRandomAccount(t), int64(n)*amount)
+	account2 := fundAccount(t, createRandomAccount(t), int64(n)*amount)
+	fmt.Println(">> before:", account1.Balance, account2.Balance)
+
 	errs := make(chan error) // Using channel to connect Go routines
 
 	for i := 0; i < n; i++ {
@@ -161,3 +168,111 @@ func TestTransferTxDeadlock(t *testing.T) {
 
 	fmt.Println(">> after:", updatedAccount1.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account1 := fundAccount(t, createRandomAccount(t), 10)
+	account2 := createRandomAccount(t)
+
+	failed := metrics.Transfers.WithLabelValues(metrics.TransferInsufficientFunds)
+	failedBefore := testutil.ToFloat64(failed)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+	require.Equal(t, failedBefore+1, testutil.ToFloat64(failed))
+
+	var fundsErr *InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	require.Equal(t, account1.ID, fundsErr.AccountID)
+	require.Equal(t, account1.Balance, fundsErr.Available)
+
+	// Nothing was written by the failed tx
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	// With an overdraft limit the same transfer goes through, and the balance goes below zero
+	_, err = testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 1,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), result.FromAccount.Balance)
+}
+
+func TestExchangeTransferTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+
+	account1 := fundAccount(t, createRandomAccount(t), 100)
+	account2 := createRandomAccount(t)
+
+	arg := ExchangeTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  "0.92",
+	}
+
+	result, err := store.ExchangeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, transfer.ExchangeRate)
+
+	// Each side moves by the amount in its own currency
+	require.Equal(t, -arg.Amount, result.FromEntry.Amount)
+	require.Equal(t, arg.ToAmount, result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-arg.Amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+arg.ToAmount, result.ToAccount.Balance)
+
+	// Transfers in a single currency have both amounts equal and a rate of 1
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), result.Transfer.ToAmount)
+	require.Equal(t, "1", result.Transfer.ExchangeRate)
+}
+
+func TestBalanceCheckConstraint(t *testing.T) {
+	account := createRandomAccount(t)
+
+	// The db rejects negative balances even if the update doesn't go thru the store
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: -1,
+	})
+	require.Error(t, err)
+	require.ErrorIs(t, asInsufficientFunds(err), ErrInsufficientFunds)
+}
+
+// fundAccount sets the balance of the account to at least the given amount
+func fundAccount(t *testing.T, account Account, balance int64) Account {
+	if account.Balance >= balance {
+		return account
+	}
+
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	return account
+}
@@ -0,0 +1,236 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: totp.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+  code_hash,
+  username
+) VALUES (
+  $1, $2
+)
+`
+
+type CreateRecoveryCodeParams struct {
+	CodeHash string `json:"code_hash"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, createRecoveryCode, arg.CodeHash, arg.Username)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, username)
+	return err
+}
+
+const disableTOTP = `-- name: DisableTOTP :one
+UPDATE users
+SET totp_enabled = false, totp_secret = '', totp_last_step = 0
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, totp_secret, totp_enabled, totp_last_step, mfa_challenge_used_at
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableTOTP, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.MfaChallengeUsedAt,
+	)
+	return i, err
+}
+
+const enableTOTP = `-- name: EnableTOTP :one
+UPDATE users
+SET totp_enabled = true, totp_last_step = $1
+WHERE username = $2 AND NOT totp_enabled AND totp_secret <> ''
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, totp_secret, totp_enabled, totp_last_step, mfa_challenge_used_at
+`
+
+type EnableTOTPParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+// The step of the code which confirmed the enrollment counts as used
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableTOTP, arg.Step, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.MfaChallengeUsedAt,
+	)
+	return i, err
+}
+
+const setTOTPSecret = `-- name: SetTOTPSecret :one
+UPDATE users
+SET totp_secret = $1, totp_last_step = 0
+WHERE username = $2 AND NOT totp_enabled
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, totp_secret, totp_enabled, totp_last_step, mfa_challenge_used_at
+`
+
+type SetTOTPSecretParams struct {
+	TotpSecret string `json:"totp_secret"`
+	Username   string `json:"username"`
+}
+
+// Starts over an enrollment, which is only possible while TOTP is not enabled yet
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setTOTPSecret, arg.TotpSecret, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.MfaChallengeUsedAt,
+	)
+	return i, err
+}
+
+const useMFAChallenge = `-- name: UseMFAChallenge :one
+UPDATE users
+SET mfa_challenge_used_at = $1
+WHERE username = $2 AND mfa_challenge_used_at < $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, totp_secret, totp_enabled, totp_last_step, mfa_challenge_used_at
+`
+
+type UseMFAChallengeParams struct {
+	IssuedAt time.Time `json:"issued_at"`
+	Username string    `json:"username"`
+}
+
+// Only succeeds for a challenge issued later than the last one used, so a challenge can't be replayed
+func (q *Queries) UseMFAChallenge(ctx context.Context, arg UseMFAChallengeParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, useMFAChallenge, arg.IssuedAt, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.MfaChallengeUsedAt,
+	)
+	return i, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET used_at = now()
+WHERE code_hash = $1
+  AND username = $2
+  AND used_at IS NULL
+RETURNING code_hash, username, used_at, created_at
+`
+
+type UseRecoveryCodeParams struct {
+	CodeHash string `json:"code_hash"`
+	Username string `json:"username"`
+}
+
+// Marks the code as used, as long as it belongs to the user & isn't used yet, so it works only once
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, useRecoveryCode, arg.CodeHash, arg.Username)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.CodeHash,
+		&i.Username,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :one
+UPDATE users
+SET totp_last_step = $1
+WHERE username = $2 AND totp_enabled AND totp_last_step < $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, totp_secret, totp_enabled, totp_last_step, mfa_challenge_used_at
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+// Only succeeds for a step later than the last one used, so a code can't be replayed
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, useTOTPStep, arg.Step, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.MfaChallengeUsedAt,
+	)
+	return i, err
+}
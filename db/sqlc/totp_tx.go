@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrTOTPNotEnrolled is returned when TOTP is confirmed for a user who has no pending enrollment
+var ErrTOTPNotEnrolled = errors.New("no pending TOTP enrollment")
+
+type EnableTOTPTxParams struct {
+	Username string `json:"username"`
+	// Time step of the code which confirmed the enrollment
+	Step               int64    `json:"step"`
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// EnableTOTPTx turns on TOTP for a user who enrolled a secret, and replaces their recovery codes
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, "EnableTOTPTx", nil, func(q *Queries) error {
+		var err error
+		result, err = q.EnableTOTP(ctx, EnableTOTPParams{
+			Username: arg.Username,
+			Step:     arg.Step,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrTOTPNotEnrolled
+			}
+			return err
+		}
+
+		if err = q.DeleteRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				CodeHash: codeHash,
+				Username: arg.Username,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// DisableTOTPTx turns off TOTP for a user, forgetting their secret & recovery codes
+func (store *SQLStore) DisableTOTPTx(ctx context.Context, username string) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, "DisableTOTPTx", nil, func(q *Queries) error {
+		var err error
+		result, err = q.DisableTOTP(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteRecoveryCodes(ctx, username)
+	})
+	return result, err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRecoveryCodeHashes(t *testing.T, n int) []string {
+	hashes := make([]string, n)
+	for i := range hashes {
+		code, err := util.NewSecureToken()
+		require.NoError(t, err)
+		hashes[i] = util.HashSecureToken(code)
+	}
+	return hashes
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	user := createRandomUser(t)
+
+	// Nothing to confirm before a secret is enrolled
+	_, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{Username: user.Username, Step: 1})
+	require.ErrorIs(t, err, ErrTOTPNotEnrolled)
+
+	enrolled, err := testQueries.SetTOTPSecret(context.Background(), SetTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: "sealed-secret",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "sealed-secret", enrolled.TotpSecret)
+	require.False(t, enrolled.TotpEnabled)
+
+	arg := EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: createRandomRecoveryCodeHashes(t, 3),
+	}
+	enabled, err := store.EnableTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, enabled.TotpEnabled)
+	require.Equal(t, arg.Step, enabled.TotpLastStep)
+
+	// The secret can't be swapped once enabled
+	_, err = testQueries.SetTOTPSecret(context.Background(), SetTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: "another-secret",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Steps can't be used twice, nor out of order
+	for _, step := range []int64{99, 100} {
+		_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: step})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+	used, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 101})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), used.TotpLastStep)
+
+	// Recovery codes work once, for their own user only
+	other := createRandomUser(t)
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: other.Username,
+		CodeHash: arg.RecoveryCodeHashes[0],
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	code, err := testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: arg.RecoveryCodeHashes[0],
+	})
+	require.NoError(t, err)
+	require.True(t, code.UsedAt.Valid)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: arg.RecoveryCodeHashes[0],
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDisableTOTPTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	user := createRandomUser(t)
+
+	_, err := testQueries.SetTOTPSecret(context.Background(), SetTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: "sealed-secret",
+	})
+	require.NoError(t, err)
+
+	codeHashes := createRandomRecoveryCodeHashes(t, 2)
+	_, err = store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               1,
+		RecoveryCodeHashes: codeHashes,
+	})
+	require.NoError(t, err)
+
+	disabled, err := store.DisableTOTPTx(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, disabled.TotpEnabled)
+	require.Empty(t, disabled.TotpSecret)
+	require.Zero(t, disabled.TotpLastStep)
+
+	// The recovery codes are gone with the secret
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: codeHashes[1],
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseMFAChallenge(t *testing.T) {
+	user := createRandomUser(t)
+	issuedAt := time.Now().Truncate(time.Microsecond)
+
+	used, err := testQueries.UseMFAChallenge(context.Background(), UseMFAChallengeParams{
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	})
+	require.NoError(t, err)
+	require.WithinDuration(t, issuedAt, used.MfaChallengeUsedAt, time.Microsecond)
+
+	// Challenges can't be used twice, and older ones stop working too
+	for _, at := range []time.Time{issuedAt, issuedAt.Add(-time.Second)} {
+		_, err = testQueries.UseMFAChallenge(context.Background(), UseMFAChallengeParams{
+			Username: user.Username,
+			IssuedAt: at,
+		})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+
+	_, err = testQueries.UseMFAChallenge(context.Background(), UseMFAChallengeParams{
+		Username: user.Username,
+		IssuedAt: issuedAt.Add(time.Second),
+	})
+	require.NoError(t, err)
+}
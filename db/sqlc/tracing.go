@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/kvnyijia/bank-app/db/sqlc"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// tracedDB makes a span for every query, named after the query in the sqlc file, e.g. db.GetAccount.
+// Within a tx, queries are children of the span of the tx rather than of the one in their ctx.
+type tracedDB struct {
+	DBTX
+	parent trace.Span
+}
+
+func (db tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := db.startSpan(ctx, query)
+	result, err := db.DBTX.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return result, err
+}
+
+func (db tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := db.startSpan(ctx, query)
+	stmt, err := db.DBTX.PrepareContext(ctx, query)
+	endSpan(span, err)
+	return stmt, err
+}
+
+func (db tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := db.startSpan(ctx, query)
+	rows, err := db.DBTX.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs the query right away, Scan only reads the row it got
+func (db tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := db.startSpan(ctx, query)
+	row := db.DBTX.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+func (db tracedDB) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if db.parent != nil {
+		ctx = trace.ContextWithSpan(ctx, db.parent)
+	}
+
+	operation := queryName(query)
+	return tracer().Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+// endSpan records the error, if any. A query which finds no row isn't a failure.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryName is the name sqlc puts on the first line of the query, i.e. "-- name: GetAccount :one"
+func queryName(query string) string {
+	line, _, _ := strings.Cut(query, "\n")
+	if name, found := strings.CutPrefix(line, "-- name: "); found {
+		if name, _, found = strings.Cut(name, " "); found {
+			return name
+		}
+	}
+	return "query"
+}
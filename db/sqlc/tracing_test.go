@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kvnyijia/bank-app/tracing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "CreateTransfer", queryName(createTransfer))
+	require.Equal(t, "query", queryName("select version, dirty from schema_migrations limit 1"))
+}
+
+func TestTransferTxSpans(t *testing.T) {
+	provider, exporter := tracing.NewInMemoryProvider()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	account1 := fundAccount(t, createRandomAccount(t), 10)
+	account2 := createRandomAccount(t)
+	exporter.Reset()
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	txSpan := findSpan(t, spans, "db.ExchangeTransferTx")
+	require.False(t, txSpan.Parent.IsValid())
+
+	// Every step of the tx is a child of its span
+	for _, name := range []string{"db.Begin", "db.GetAccountForUpdate", "db.CreateTransfer", "db.CreateEntry", "db.UpdateAccountBalance", "db.Commit"} {
+		span := findSpan(t, spans, name)
+		require.Equal(t, txSpan.SpanContext.SpanID(), span.Parent.SpanID(), name)
+	}
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "no span named %s", name)
+	return tracetest.SpanStub{}
+}
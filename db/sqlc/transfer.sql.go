@@ -7,22 +7,31 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
-insert into transfers (from_account_id, to_account_id, amount) 
-VALUES ($1, $2, $3)
-RETURNING id, from_account_id, to_account_id, amount, created_at
+insert into transfers (from_account_id, to_account_id, amount, to_amount, exchange_rate) 
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	ToAmount      int64  `json:"to_amount"`
+	ExchangeRate  string `json:"exchange_rate"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -30,12 +39,14 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-select id, from_account_id, to_account_id, amount, created_at 
+select id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate 
 from transfers
 where id = $1
 limit 1
@@ -50,12 +61,100 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
 	)
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+select id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate
+from transfers
+where (
+    (from_account_id = $1 and coalesce($2::text, 'outgoing') = 'outgoing')
+    or (to_account_id = $1 and coalesce($2::text, 'incoming') = 'incoming')
+  )
+  and (
+    $3::bigint is null
+    or from_account_id = $3
+    or to_account_id = $3
+  )
+  and ($4::timestamptz is null or created_at >= $4)
+  and ($5::timestamptz is null or created_at < $5)
+  and (
+    $6::bigint is null
+    or (case when from_account_id = $1 then amount else to_amount end) >= $6
+  )
+  and (
+    $7::bigint is null
+    or (case when from_account_id = $1 then amount else to_amount end) <= $7
+  )
+  and (
+    $8::timestamptz is null
+    or (created_at, id) < ($8, $9::bigint)
+  )
+order by created_at desc, id desc
+limit $10
+`
+
+type ListAccountTransfersParams struct {
+	AccountID             int64          `json:"account_id"`
+	Direction             sql.NullString `json:"direction"`
+	CounterpartyAccountID sql.NullInt64  `json:"counterparty_account_id"`
+	StartTime             sql.NullTime   `json:"start_time"`
+	EndTime               sql.NullTime   `json:"end_time"`
+	MinAmount             sql.NullInt64  `json:"min_amount"`
+	MaxAmount             sql.NullInt64  `json:"max_amount"`
+	BeforeCreatedAt       sql.NullTime   `json:"before_created_at"`
+	BeforeID              sql.NullInt64  `json:"before_id"`
+	Limit                 int32          `json:"limit"`
+}
+
+// Newest first. Amounts are compared in the currency of the given account.
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.AccountID,
+		arg.Direction,
+		arg.CounterpartyAccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTarnsfer = `-- name: ListTarnsfer :many
-select id, from_account_id, to_account_id, amount, created_at
+select id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate
 from transfers
 where from_account_id = $1 and to_account_id = $2
 order by id
@@ -90,6 +189,8 @@ func (q *Queries) ListTarnsfer(ctx context.Context, arg ListTarnsferParams) ([]T
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
 		); err != nil {
 			return nil, err
 		}
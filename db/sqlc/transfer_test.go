@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createTransferWithAmounts(t *testing.T, from Account, to Account, amount int64, toAmount int64) Transfer {
+	transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		ToAmount:      toAmount,
+		ExchangeRate:  "1",
+	})
+	require.NoError(t, err)
+	return transfer
+}
+
+func TestListAccountTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	account3 := createRandomAccount(t)
+
+	outgoing := createTransferWithAmounts(t, account1, account2, 10, 10)
+	incoming := createTransferWithAmounts(t, account3, account1, 100, 50)
+	createTransferWithAmounts(t, account2, account3, 10, 10)
+
+	arg := ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	}
+
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{incoming, outgoing}, transfers)
+
+	arg.Direction = sql.NullString{String: "outgoing", Valid: true}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{outgoing}, transfers)
+
+	arg.Direction = sql.NullString{}
+	arg.CounterpartyAccountID = sql.NullInt64{Int64: account3.ID, Valid: true}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{incoming}, transfers)
+
+	// Incoming transfers are filtered by the amount credited to the account
+	arg.CounterpartyAccountID = sql.NullInt64{}
+	arg.MinAmount = sql.NullInt64{Int64: 20, Valid: true}
+	arg.MaxAmount = sql.NullInt64{Int64: 60, Valid: true}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{incoming}, transfers)
+}
@@ -29,6 +29,12 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
 
+	require.Equal(t, util.DepositorRole, user.Role)
+	require.False(t, user.IsEmailVerified)
+	require.False(t, user.TotpEnabled)
+	require.Empty(t, user.TotpSecret)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.True(t, user.LockedUntil.Before(time.Now()))
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
 	return user
@@ -51,3 +57,55 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func TestGetUserByEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2, err := testQueries.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	user2, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     util.BankerRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, util.BankerRole, user2.Role)
+}
+
+func TestRecordFailedLogin(t *testing.T) {
+	user := createRandomUser(t)
+	arg := RecordFailedLoginParams{
+		Username:          user.Username,
+		MaxAttempts:       3,
+		LockoutSeconds:    60,
+		MaxLockoutSeconds: 150,
+	}
+
+	// Below the max attempts, failures are only counted
+	for i := 1; i < 3; i++ {
+		user, err := testQueries.RecordFailedLogin(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, int32(i), user.FailedLoginAttempts)
+		require.True(t, user.LockedUntil.Before(time.Now()))
+	}
+
+	// Then every failure locks the user for twice as long as the last one, up to the max lockout
+	for _, lockout := range []time.Duration{time.Minute, 2 * time.Minute, 150 * time.Second, 150 * time.Second} {
+		user, err := testQueries.RecordFailedLogin(context.Background(), arg)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now().Add(lockout), user.LockedUntil, 5*time.Second)
+	}
+
+	err := testQueries.ResetFailedLogins(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	user, err = testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.True(t, user.LockedUntil.Before(time.Now()))
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrInvalidVerifyEmail is returned when an email verification code is wrong, expired or already used
+var ErrInvalidVerifyEmail = errors.New("email verification code is invalid or expired")
+
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates the user along with the email to verify their address, which the email worker sends later.
+// Both are written in one tx, so no user is left without one.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, "CreateUserTx", nil, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username: result.User.Username,
+			Email:    result.User.Email,
+		})
+		return err
+	})
+	return result, err
+}
+
+// CreateVerifiedUserTx creates a user whose email is known to be theirs already, e.g. checked by an operator,
+// so no email is sent to verify it. The user is never left unverified.
+func (store *SQLStore) CreateVerifiedUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, "CreateVerifiedUserTx", nil, func(q *Queries) error {
+		user, err := q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: user.Username,
+			Email:    user.Email,
+		})
+		return err
+	})
+	return result, err
+}
+
+type VerifyEmailTxParams struct {
+	EmailID        int64  `json:"email_id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// VerifyEmailTx uses up the code of a verification email & marks the address of its user as verified
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, "VerifyEmailTx", nil, func(q *Queries) error {
+		verifyEmail, err := q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:             arg.EmailID,
+			SecretCodeHash: arg.SecretCodeHash,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidVerifyEmail
+			}
+			return err
+		}
+
+		result, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: verifyEmail.Username,
+			Email:    verifyEmail.Email,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// The user changed their email since the code was sent
+			return ErrInvalidVerifyEmail
+		}
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTx(t *testing.T, store Store) CreateUserTxResult {
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	return result
+}
+
+// claimUserVerifyEmail takes due emails until it gets the one of the user, as other tests may have queued some too
+func claimUserVerifyEmail(t *testing.T, username string, secretCode string) VerifyEmail {
+	for {
+		verifyEmail, err := testQueries.ClaimVerifyEmail(context.Background(), ClaimVerifyEmailParams{
+			SecretCodeHash: util.HashSecureToken(secretCode),
+			RetrySeconds:   60,
+			ExpireSeconds:  3600,
+			MaxAttempts:    3,
+		})
+		require.NoError(t, err)
+		if verifyEmail.Username == username {
+			return verifyEmail
+		}
+	}
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	result := createRandomUserTx(t, store)
+
+	require.False(t, result.User.IsEmailVerified)
+
+	// The email is queued to be sent right away, without a code yet
+	verifyEmail := result.VerifyEmail
+	require.NotZero(t, verifyEmail.ID)
+	require.Equal(t, result.User.Username, verifyEmail.Username)
+	require.Equal(t, result.User.Email, verifyEmail.Email)
+	require.Empty(t, verifyEmail.SecretCodeHash)
+	require.False(t, verifyEmail.SentAt.Valid)
+	require.False(t, verifyEmail.NextSendAt.After(time.Now()))
+
+	// No user is created without the email, and no email without the user
+	_, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       result.User.Username,
+		HashedPassword: result.User.HashedPassword,
+		FullName:       result.User.FullName,
+		Email:          util.RandomEmail(),
+	})
+	require.Error(t, err)
+}
+
+func TestCreateVerifiedUserTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	arg := CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "hashed-password",
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+
+	user, err := store.CreateVerifiedUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, user.Username)
+	require.True(t, user.IsEmailVerified)
+
+	got, err := testQueries.GetUser(context.Background(), arg.Username)
+	require.NoError(t, err)
+	require.True(t, got.IsEmailVerified)
+
+	_, err = store.CreateVerifiedUserTx(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestClaimVerifyEmail(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	result := createRandomUserTx(t, store)
+
+	verifyEmail := claimUserVerifyEmail(t, result.User.Username, "code")
+	require.Equal(t, util.HashSecureToken("code"), verifyEmail.SecretCodeHash)
+	require.Equal(t, int32(1), verifyEmail.SendAttempts)
+	require.WithinDuration(t, time.Now().Add(time.Minute), verifyEmail.NextSendAt, 5*time.Second)
+	require.WithinDuration(t, time.Now().Add(time.Hour), verifyEmail.ExpiredAt, 5*time.Second)
+
+	err := testQueries.MarkVerifyEmailSent(context.Background(), verifyEmail.ID)
+	require.NoError(t, err)
+
+	var sentAt sql.NullTime
+	err = testDB.QueryRowContext(context.Background(), "SELECT sent_at FROM verify_emails WHERE id = $1", verifyEmail.ID).Scan(&sentAt)
+	require.NoError(t, err)
+	require.True(t, sentAt.Valid)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop(), testRetryPolicy)
+	result := createRandomUserTx(t, store)
+	verifyEmail := claimUserVerifyEmail(t, result.User.Username, "code")
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        verifyEmail.ID,
+		SecretCodeHash: util.HashSecureToken("wrong"),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+
+	arg := VerifyEmailTxParams{
+		EmailID:        verifyEmail.ID,
+		SecretCodeHash: util.HashSecureToken("code"),
+	}
+	user, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, result.User.Username, user.Username)
+	require.True(t, user.IsEmailVerified)
+
+	// Codes work only once
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+}
@@ -0,0 +1,139 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: verify_email.sql
+
+package db
+
+import (
+	"context"
+)
+
+const claimVerifyEmail = `-- name: ClaimVerifyEmail :one
+UPDATE verify_emails
+SET
+  secret_code_hash = $1,
+  send_attempts = send_attempts + 1,
+  next_send_at = now() + make_interval(secs => $2::float8 * power(2, LEAST(send_attempts, 30))),
+  expired_at = now() + make_interval(secs => $3::float8)
+WHERE id = (
+  SELECT id FROM verify_emails
+  WHERE sent_at IS NULL
+    AND send_attempts < $4::int
+    AND next_send_at <= now()
+  ORDER BY next_send_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, username, email, secret_code_hash, is_used, send_attempts, next_send_at, sent_at, created_at, expired_at
+`
+
+type ClaimVerifyEmailParams struct {
+	SecretCodeHash string  `json:"secret_code_hash"`
+	RetrySeconds   float64 `json:"retry_seconds"`
+	ExpireSeconds  float64 `json:"expire_seconds"`
+	MaxAttempts    int32   `json:"max_attempts"`
+}
+
+// Takes the next email due to be sent & sets its code. The email isn't due again until retry_seconds later,
+// twice as long after every attempt, so other workers leave it alone while it's sent & it's retried if the send fails.
+func (q *Queries) ClaimVerifyEmail(ctx context.Context, arg ClaimVerifyEmailParams) (VerifyEmail, error) {
+	row := q.db.QueryRowContext(ctx, claimVerifyEmail,
+		arg.SecretCodeHash,
+		arg.RetrySeconds,
+		arg.ExpireSeconds,
+		arg.MaxAttempts,
+	)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCodeHash,
+		&i.IsUsed,
+		&i.SendAttempts,
+		&i.NextSendAt,
+		&i.SentAt,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const createVerifyEmail = `-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email
+) VALUES (
+  $1, $2
+) RETURNING id, username, email, secret_code_hash, is_used, send_attempts, next_send_at, sent_at, created_at, expired_at
+`
+
+type CreateVerifyEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error) {
+	row := q.db.QueryRowContext(ctx, createVerifyEmail, arg.Username, arg.Email)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCodeHash,
+		&i.IsUsed,
+		&i.SendAttempts,
+		&i.NextSendAt,
+		&i.SentAt,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const markVerifyEmailSent = `-- name: MarkVerifyEmailSent :exec
+UPDATE verify_emails
+SET sent_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkVerifyEmailSent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markVerifyEmailSent, id)
+	return err
+}
+
+const useVerifyEmail = `-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1
+  AND secret_code_hash = $2
+  AND secret_code_hash != ''
+  AND is_used = false
+  AND expired_at > now()
+RETURNING id, username, email, secret_code_hash, is_used, send_attempts, next_send_at, sent_at, created_at, expired_at
+`
+
+type UseVerifyEmailParams struct {
+	ID             int64  `json:"id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// Marks the code as used, as long as it's right & neither used nor expired yet
+func (q *Queries) UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error) {
+	row := q.db.QueryRowContext(ctx, useVerifyEmail, arg.ID, arg.SecretCodeHash)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCodeHash,
+		&i.IsUsed,
+		&i.SendAttempts,
+		&i.NextSendAt,
+		&i.SentAt,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
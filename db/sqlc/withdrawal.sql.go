@@ -0,0 +1,55 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: withdrawal.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createWithdrawal = `-- name: CreateWithdrawal :one
+insert into withdrawals (account_id, amount, destination)
+values ($1, $2, $3)
+RETURNING id, account_id, amount, destination, created_at
+`
+
+type CreateWithdrawalParams struct {
+	AccountID   int64  `json:"account_id"`
+	Amount      int64  `json:"amount"`
+	Destination string `json:"destination"`
+}
+
+func (q *Queries) CreateWithdrawal(ctx context.Context, arg CreateWithdrawalParams) (Withdrawal, error) {
+	row := q.db.QueryRowContext(ctx, createWithdrawal, arg.AccountID, arg.Amount, arg.Destination)
+	var i Withdrawal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.Destination,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWithdrawal = `-- name: GetWithdrawal :one
+select id, account_id, amount, destination, created_at
+from withdrawals
+where id = $1
+limit 1
+`
+
+func (q *Queries) GetWithdrawal(ctx context.Context, id int64) (Withdrawal, error) {
+	row := q.db.QueryRowContext(ctx, getWithdrawal, id)
+	var i Withdrawal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.Destination,
+		&i.CreatedAt,
+	)
+	return i, err
+}
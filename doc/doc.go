@@ -0,0 +1,134 @@
+// Package doc serves the OpenAPI specs of the APIs, and a Swagger UI for them.
+// The spec of the gateway routes is generated from the proto definitions, the one of the Gin routes is maintained by hand.
+package doc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	openapi "github.com/google/gnostic/openapiv3"
+	"github.com/kvnyijia/bank-app/pb"
+	swaggerFiles "github.com/swaggo/files"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+//go:embed openapi.yaml
+var openapiYAML []byte
+
+//go:embed http_openapi.yaml
+var httpOpenapiYAML []byte
+
+//go:embed swagger/swagger-initializer.js
+var swaggerInitializer []byte
+
+// Spec returns the OpenAPI spec of the gateway routes as JSON
+func Spec() ([]byte, error) {
+	document, err := openapi.ParseDocument(openapiYAML)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse openapi spec: %w", err)
+	}
+
+	if err := applyParameterRules(document); err != nil {
+		return nil, err
+	}
+	return toJSON(document)
+}
+
+// HTTPSpec returns the OpenAPI spec of the Gin routes as JSON
+func HTTPSpec() ([]byte, error) {
+	document, err := openapi.ParseDocument(httpOpenapiYAML)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse http openapi spec: %w", err)
+	}
+	return toJSON(document)
+}
+
+func toJSON(document *openapi.Document) ([]byte, error) {
+	var spec any
+	if err := document.ToRawInfo().Decode(&spec); err != nil {
+		return nil, fmt.Errorf("cannot decode openapi spec: %w", err)
+	}
+	return json.Marshal(spec)
+}
+
+// applyParameterRules copies the openapi.v3.property options of the request fields onto the query & path parameters.
+// protoc-gen-openapi only applies them to the schemas of request bodies.
+func applyParameterRules(document *openapi.Document) error {
+	service := pb.File_service_bank_proto.Services().ByName("Bank")
+
+	for _, item := range document.GetPaths().GetPath() {
+		for _, operation := range operations(item.GetValue()) {
+			name, ok := strings.CutPrefix(operation.GetOperationId(), "Bank_")
+			if !ok {
+				continue
+			}
+			method := service.Methods().ByName(protoreflect.Name(name))
+			if method == nil {
+				return fmt.Errorf("no RPC for operation %s", operation.GetOperationId())
+			}
+
+			for _, parameter := range operation.GetParameters() {
+				param := parameter.GetParameter()
+				field := method.Input().Fields().ByName(protoreflect.Name(param.GetName()))
+				schema := param.GetSchema().GetSchema()
+				if field == nil || schema == nil {
+					continue
+				}
+
+				property, ok := proto.GetExtension(field.Options(), openapi.E_Property).(*openapi.Schema)
+				if ok && property != nil {
+					proto.Merge(schema, property)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func operations(item *openapi.PathItem) []*openapi.Operation {
+	var result []*openapi.Operation
+	for _, operation := range []*openapi.Operation{item.Get, item.Put, item.Post, item.Delete, item.Patch} {
+		if operation != nil {
+			result = append(result, operation)
+		}
+	}
+	return result
+}
+
+// NewHandler serves the spec of the gateway routes at /openapi.json and the Swagger UI under /swagger/
+func NewHandler() (http.Handler, error) {
+	spec, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+	return newHandler(spec), nil
+}
+
+// NewHTTPHandler serves the spec of the Gin routes at /openapi.json and the Swagger UI under /swagger/
+func NewHTTPHandler() (http.Handler, error) {
+	spec, err := HTTPSpec()
+	if err != nil {
+		return nil, err
+	}
+	return newHandler(spec), nil
+}
+
+func newHandler(spec []byte) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+
+	// The Swagger UI assets are the stock ones, but the initializer points them at our spec
+	mux.HandleFunc("/swagger/swagger-initializer.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Write(swaggerInitializer)
+	})
+	mux.Handle("/swagger/", http.StripPrefix("/swagger", http.FileServer(swaggerFiles.HTTP)))
+	return mux
+}
@@ -0,0 +1,84 @@
+package doc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	data, err := Spec()
+	require.NoError(t, err)
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Parameters []struct {
+				Name   string         `json:"name"`
+				Schema map[string]any `json:"schema"`
+			} `json:"parameters"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string                  `json:"required"`
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &spec))
+
+	createAccount := spec.Components.Schemas["CreateAccountRequest"]
+	require.Equal(t, []string{"currency"}, createAccount.Required)
+	require.ElementsMatch(t, []any{"USD", "EUR", "CAD"}, createAccount.Properties["currency"]["enum"])
+
+	createTransfer := spec.Components.Schemas["CreateTransferRequest"]
+	require.Equal(t, float64(1), createTransfer.Properties["amount"]["minimum"])
+
+	// Rules of query & path params come from the request fields as well
+	params := spec.Paths["/v1/accounts"]["get"].Parameters
+	require.Len(t, params, 2)
+	require.Equal(t, "page_size", params[0].Name)
+	require.Equal(t, float64(1), params[0].Schema["minimum"])
+	require.Equal(t, float64(100), params[0].Schema["maximum"])
+	require.Equal(t, float64(20), params[0].Schema["default"])
+
+	params = spec.Paths["/v1/accounts/{id}"]["get"].Parameters
+	require.Len(t, params, 1)
+	require.Equal(t, float64(1), params[0].Schema["minimum"])
+}
+
+func TestHTTPSpec(t *testing.T) {
+	data, err := HTTPSpec()
+	require.NoError(t, err)
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(data, &spec))
+	require.Equal(t, "createDeposit", spec.Paths["/accounts/{id}/deposits"]["post"].OperationID)
+	require.Equal(t, "listTransfers", spec.Paths["/transfers"]["get"].OperationID)
+}
+
+func TestHandler(t *testing.T) {
+	handler, err := NewHandler()
+	require.NoError(t, err)
+
+	for _, path := range []string{"/openapi.json", "/swagger/", "/swagger/swagger-initializer.js", "/swagger/swagger-ui-bundle.js"} {
+		recorder := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, path)
+	}
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/swagger/swagger-initializer.js", nil)
+	require.NoError(t, err)
+	handler.ServeHTTP(recorder, req)
+	require.Contains(t, recorder.Body.String(), `"/openapi.json"`)
+}
@@ -0,0 +1,15 @@
+package fx
+
+import "github.com/kvnyijia/bank-app/util"
+
+// NewRateProviderFromConfig picks the rates service when its URL is configured, then the rates file.
+// It returns a nil provider when neither is set, which disables currency exchange.
+func NewRateProviderFromConfig(config util.Config) (RateProvider, error) {
+	if len(config.FXRatesURL) > 0 {
+		return NewHTTPProvider(config.FXRatesURL, nil, config.FXRatesTTL), nil
+	}
+	if len(config.FXRatesFile) > 0 {
+		return LoadStaticProvider(config.FXRatesFile)
+	}
+	return nil, nil
+}
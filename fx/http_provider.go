@@ -0,0 +1,84 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPProvider fetches the rate table from a rates service, and caches it for a while
+type HTTPProvider struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	cached    *StaticProvider
+	fetchedAt time.Time
+}
+
+func NewHTTPProvider(url string, client *http.Client, ttl time.Duration) *HTTPProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &HTTPProvider{
+		url:    url,
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (provider *HTTPProvider) GetRate(ctx context.Context, from string, to string) (Rate, error) {
+	rates, err := provider.rates(ctx)
+	if err != nil {
+		return Rate{}, err
+	}
+	return rates.GetRate(ctx, from, to)
+}
+
+func (provider *HTTPProvider) rates(ctx context.Context) (*StaticProvider, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.cached != nil && time.Since(provider.fetchedAt) < provider.ttl {
+		return provider.cached, nil
+	}
+
+	rates, err := provider.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.cached = rates
+	provider.fetchedAt = time.Now()
+	return rates, nil
+}
+
+func (provider *HTTPProvider) fetch(ctx context.Context) (*StaticProvider, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := provider.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch rates: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch rates: unexpected status %d", rsp.StatusCode)
+	}
+
+	var table RateTable
+	decoder := json.NewDecoder(rsp.Body)
+	decoder.UseNumber()
+	err = decoder.Decode(&table)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rates: %w", err)
+	}
+	return NewStaticProvider(table)
+}
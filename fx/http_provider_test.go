@@ -0,0 +1,64 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Rates services usually send numbers rather than strings
+		fmt.Fprint(w, `{"base": "USD", "rates": {"EUR": 0.92, "CAD": 1.36}}`)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, nil, time.Minute)
+
+	rate, err := provider.GetRate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "0.92", rate.Value)
+
+	// The second lookup is served from the cache
+	rate, err = provider.GetRate(context.Background(), "CAD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "0.7352941176", rate.Value)
+	require.Equal(t, 1, requests)
+}
+
+func TestHTTPProviderExpiredCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"base": "USD", "rates": {"EUR": 0.92}}`)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, nil, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err := provider.GetRate(context.Background(), "USD", "EUR")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, requests)
+}
+
+func TestHTTPProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, nil, time.Minute)
+
+	_, err := provider.GetRate(context.Background(), "USD", "EUR")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrRateNotFound)
+}
@@ -0,0 +1,56 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Number of decimal places kept when a rate has to be derived from others
+const ratePrecision = 10
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// Rate tells how many units of the To currency one unit of the From currency buys.
+// The value is a decimal string, so no precision is lost on the way to the db.
+type Rate struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value"`
+}
+
+// RateProvider looks up the current exchange rate between two currencies
+type RateProvider interface {
+	GetRate(ctx context.Context, from string, to string) (Rate, error)
+}
+
+// Convert turns an amount in the From currency into the To currency, rounded half away from zero.
+// Amounts are in minor units, which works since every supported currency has 2 decimal places.
+func (rate Rate) Convert(amount int64) (int64, error) {
+	value, ok := new(big.Rat).SetString(rate.Value)
+	if !ok {
+		return 0, fmt.Errorf("invalid exchange rate %q", rate.Value)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), value)
+	return roundRat(converted)
+}
+
+func roundRat(value *big.Rat) (int64, error) {
+	num := new(big.Int).Abs(value.Num())
+	quo, rem := new(big.Int).QuoRem(num, value.Denom(), new(big.Int))
+
+	// Round up when the remainder is at least half of the denominator
+	if new(big.Int).Mul(rem, big.NewInt(2)).Cmp(value.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quo.Neg(quo)
+	}
+
+	if !quo.IsInt64() {
+		return 0, errors.New("converted amount is out of range")
+	}
+	return quo.Int64(), nil
+}
@@ -0,0 +1,35 @@
+package fx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rate     string
+		amount   int64
+		expected int64
+	}{
+		{name: "Identity", rate: "1", amount: 1234, expected: 1234},
+		{name: "Exact", rate: "0.92", amount: 100, expected: 92},
+		{name: "RoundDown", rate: "0.921", amount: 100, expected: 92},
+		{name: "RoundHalfUp", rate: "0.925", amount: 100, expected: 93},
+		{name: "Fraction", rate: "1.0869565217", amount: 92, expected: 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			converted, err := Rate{Value: tc.rate}.Convert(tc.amount)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, converted)
+		})
+	}
+}
+
+func TestConvertInvalidRate(t *testing.T) {
+	_, err := Rate{Value: "abc"}.Convert(100)
+	require.Error(t, err)
+}
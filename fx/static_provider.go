@@ -0,0 +1,84 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// RateTable is the JSON format shared by the static and the HTTP provider:
+// the value of one unit of the base currency in every other currency
+type RateTable struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// StaticProvider serves rates from a fixed table, e.g. for tests or when running offline
+type StaticProvider struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+func NewStaticProvider(table RateTable) (*StaticProvider, error) {
+	if len(table.Base) == 0 {
+		return nil, fmt.Errorf("base currency must not be empty")
+	}
+
+	provider := &StaticProvider{
+		base:  table.Base,
+		rates: map[string]*big.Rat{table.Base: big.NewRat(1, 1)},
+	}
+	for currency, number := range table.Rates {
+		value, ok := new(big.Rat).SetString(number.String())
+		if !ok || value.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", number, currency)
+		}
+		provider.rates[currency] = value
+	}
+	return provider, nil
+}
+
+// LoadStaticProvider reads the rate table from a JSON file
+func LoadStaticProvider(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	var table RateTable
+	err = json.Unmarshal(data, &table)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rates file: %w", err)
+	}
+	return NewStaticProvider(table)
+}
+
+func (provider *StaticProvider) GetRate(ctx context.Context, from string, to string) (Rate, error) {
+	fromRate, ok := provider.rates[from]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+	}
+
+	toRate, ok := provider.rates[to]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+	}
+
+	// Both rates are relative to the base, so the cross rate is their ratio
+	value := new(big.Rat).Quo(toRate, fromRate)
+	return Rate{
+		From:  from,
+		To:    to,
+		Value: formatRate(value),
+	}, nil
+}
+
+// formatRate rounds the rate to ratePrecision decimal places, and drops trailing zeros
+func formatRate(value *big.Rat) string {
+	s := value.FloatString(ratePrecision)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,45 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticProvider(t *testing.T) {
+	provider, err := LoadStaticProvider("testdata/rates.json")
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, Rate{From: "USD", To: "EUR", Value: "0.92"}, rate)
+
+	rate, err = provider.GetRate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "1.0869565217", rate.Value)
+
+	// Cross rates are derived from the base
+	rate, err = provider.GetRate(context.Background(), "EUR", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1.4782608696", rate.Value)
+
+	rate, err = provider.GetRate(context.Background(), "CAD", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1", rate.Value)
+
+	_, err = provider.GetRate(context.Background(), "USD", "JPY")
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestNewStaticProviderInvalidRate(t *testing.T) {
+	_, err := NewStaticProvider(RateTable{Base: "USD", Rates: map[string]json.Number{"EUR": "0"}})
+	require.Error(t, err)
+
+	_, err = NewStaticProvider(RateTable{Rates: map[string]json.Number{"EUR": "0.92"}})
+	require.Error(t, err)
+
+	_, err = LoadStaticProvider("testdata/missing.json")
+	require.Error(t, err)
+}
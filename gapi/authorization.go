@@ -0,0 +1,109 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kvnyijia/bank-app/auth"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const authorizationHeader = "authorization"
+
+// Methods which can be called without an access token
+var publicMethods = map[string]bool{
+	pb.Bank_CreateUser_FullMethodName:   true,
+	pb.Bank_LoginUser_FullMethodName:    true,
+	pb.Bank_LoginUserMFA_FullMethodName: true,
+}
+
+type authPayloadKey struct{}
+
+// authInterceptor does for gRPC what authMiddleware does for Gin: the payload of a valid token
+// of an active session is put into the context, and every other call is rejected
+func (server *Server) authInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	payload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.WithUsername(ctx, payload.Username)
+	return handler(context.WithValue(ctx, authPayloadKey{}, payload), req)
+}
+
+func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+
+	var authorization string
+	if values := md.Get(authorizationHeader); len(values) > 0 {
+		authorization = values[0]
+	}
+
+	payload, err := server.sessions.Authorize(ctx, authorization)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+	return payload, nil
+}
+
+// checkVerifiedEmail fails unless the user verified their email, if the config asks for it
+func (server *Server) checkVerifiedEmail(ctx context.Context, username string) error {
+	if !server.config.TransfersRequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot get user: %s", err)
+	}
+
+	if !user.IsEmailVerified {
+		return status.Error(codes.FailedPrecondition, "email must be verified first")
+	}
+	return nil
+}
+
+// checkStepUp fails for transfers of at least the threshold of their currency, unless the user logged in
+// or stepped up recently enough
+func (server *Server) checkStepUp(payload *token.Payload, currency string, amount int64) error {
+	threshold, ok := server.stepUpThresholds[currency]
+	if !ok || amount < threshold {
+		return nil
+	}
+
+	if time.Since(payload.AuthTime) > server.config.StepUpMaxAge {
+		return status.Error(codes.Unauthenticated, "a recent authentication is required, step up first")
+	}
+	return nil
+}
+
+// authPayload is the payload put into the context by authInterceptor
+func authPayload(ctx context.Context) (*token.Payload, error) {
+	payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no authorization payload")
+	}
+	return payload, nil
+}
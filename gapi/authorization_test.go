@@ -0,0 +1,190 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name        string
+		buildStubs  func(store *mockdb.MockStore)
+		buildCtx    func(t *testing.T, tokenMaker token.Maker) context.Context
+		expectedErr codes.Code
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			},
+			expectedErr: codes.OK,
+		},
+		{
+			name: "NoAuthorization",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "UnsupportedAuthorization",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				md := metadata.MD{authorizationHeader: []string{"basic abc"}}
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "ExpiredToken",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, -time.Minute)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "MFAChallengeToken",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				mfaToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+					Username: user.Username,
+					Role:     util.DepositorRole,
+					Duration: time.Minute,
+					Purpose:  token.PurposeMFAChallenge,
+				})
+				require.NoError(t, err)
+				md := metadata.MD{authorizationHeader: []string{auth.AuthorizationTypeBearer + " " + mfaToken}}
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "RefreshToken",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				refreshToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+					Username: user.Username,
+					Role:     util.DepositorRole,
+					Duration: time.Hour,
+					Purpose:  token.PurposeRefresh,
+				})
+				require.NoError(t, err)
+				md := metadata.MD{authorizationHeader: []string{auth.AuthorizationTypeBearer + " " + refreshToken}}
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "BlockedSession",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{Session: db.Session{Username: user.Username, IsBlocked: true, ExpiresAt: time.Now().Add(time.Minute)}}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "SessionNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAuthSessionRow{}, sql.ErrNoRows)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "IncorrectSessionUser",
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, "someone_else")
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "PasswordChanged",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthSessionRow{
+						Session:           db.Session{Username: user.Username, ExpiresAt: time.Now().Add(time.Minute)},
+						PasswordChangedAt: time.Now().Add(time.Second),
+					}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAuthSessionRow{}, sql.ErrConnDone)
+			},
+			buildCtx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			},
+			expectedErr: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+
+			ctx := tc.buildCtx(t, server.tokenMaker)
+			_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+			require.Equal(t, tc.expectedErr, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/textproto"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/kvnyijia/bank-app/doc"
+	"github.com/kvnyijia/bank-app/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NewGatewayHandler serves the HTTP routes of the proto definitions, along with their OpenAPI spec & Swagger UI.
+// Calls are forwarded to the gRPC server over conn, so they go thru the auth interceptor like any other call.
+func NewGatewayHandler(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	grpcMux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true, // snake_case like the Gin API & the spec
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+	)
+
+	err := pb.RegisterBankHandler(ctx, grpcMux, conn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot register gateway handler: %w", err)
+	}
+
+	docHandler, err := doc.NewHandler()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create doc handler: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", grpcMux)
+	mux.Handle("/", docHandler)
+	return mux, nil
+}
+
+// incomingHeaderMatcher passes the X-Request-ID header on to the gRPC server, on top of the default headers
+func incomingHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == textproto.CanonicalMIMEHeaderKey(requestIDHeader) {
+		return requestIDHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher sends the request ID of the call back as X-Request-ID, like the Gin API does
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == requestIDHeader {
+		return textproto.CanonicalMIMEHeaderKey(requestIDHeader), true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
@@ -0,0 +1,136 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayGetAccount(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		url           string
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			url:  fmt.Sprintf("/v1/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addBearerToken(t, req, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Account map[string]any `json:"account"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.Account["owner"])
+				require.Equal(t, account.Currency, rsp.Account["currency"])
+				require.Contains(t, rsp.Account, "created_at")
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			url:       fmt.Sprintf("/v1/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			url:  "/v1/accounts?page_size=101",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addBearerToken(t, req, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RequestID",
+			url:  fmt.Sprintf("/v1/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addBearerToken(t, req, tokenMaker, user.Username)
+				req.Header.Set("X-Request-ID", "abc-123")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildSessionStub(store, user.Username)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "abc-123", recorder.Header().Get("X-Request-ID"))
+			},
+		},
+		{
+			name:       "OpenAPISpec",
+			url:        "/openapi.json",
+			setupAuth:  func(t *testing.T, req *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			handler, err := NewGatewayHandler(context.Background(), newTestConn(t, server))
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, req, server.tokenMaker)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func addBearerToken(t *testing.T, req *http.Request, tokenMaker token.Maker, username string) {
+	accessToken, _, err := tokenMaker.CreateToken(token.CreateTokenParams{
+		Username: username,
+		Role:     util.DepositorRole,
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+}
@@ -0,0 +1,63 @@
+package gapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDHeader is the metadata the gateway puts the X-Request-ID header into
+const requestIDHeader = "x-request-id"
+
+// loggerInterceptor does for gRPC what requestLogger & requestMetrics do for Gin: the call gets a request ID,
+// its ctx carries a logger tagged with it, and the call is logged & timed once it's handled
+func (server *Server) loggerInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	requestID = logging.RequestID(requestID)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+
+	logger := logging.ForRequest(ctx, server.logger, requestID)
+	ctx = logger.WithContext(ctx)
+
+	start := time.Now()
+	rsp, err := handler(ctx, req)
+	code := status.Code(err)
+	metrics.GRPCRequestDuration.WithLabelValues(info.FullMethod, code.String()).Observe(time.Since(start).Seconds())
+
+	// The auth interceptor may have added the username to the logger of the call
+	reqLogger := zerolog.Ctx(ctx)
+	event := reqLogger.Info()
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		event = reqLogger.Error().Err(err)
+	default:
+		event = reqLogger.Warn().Err(err)
+	}
+
+	// Reqs are never logged, since they may hold passwords
+	event.
+		Str("protocol", "grpc").
+		Str("method", info.FullMethod).
+		Str("status_code", code.String()).
+		Dur("latency", time.Since(start)).
+		Msg("handled request")
+	return rsp, err
+}
@@ -0,0 +1,122 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kvnyijia/bank-app/auth"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		CursorSigningKey:     util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		FXRatesFile:          "../fx/testdata/rates.json",
+		LoginMaxAttempts:     3,
+		LoginLockout:         time.Minute,
+		LoginMaxLockout:      time.Hour,
+		TOTPEncryptionKey:    util.RandomString(32),
+		MFAChallengeDuration: 5 * time.Minute,
+		StepUpMaxAge:         5 * time.Minute,
+	}
+
+	server, err := NewServer(config, store, ratelimit.NewMemoryStore(), zerolog.Nop())
+	require.NoError(t, err)
+	return server
+}
+
+// newTestClient serves the server in memory, so calls go thru the interceptor like real ones
+func newTestClient(t *testing.T, server *Server) pb.BankClient {
+	return pb.NewBankClient(newTestConn(t, server))
+}
+
+func newTestConn(t *testing.T, server *Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.NewGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, role string, duration time.Duration) context.Context {
+	return newContextWithTokenParams(t, tokenMaker, token.CreateTokenParams{
+		Username: username,
+		Role:     role,
+		Duration: duration,
+	})
+}
+
+func newContextWithTokenParams(t *testing.T, tokenMaker token.Maker, params token.CreateTokenParams) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(params)
+	require.NoError(t, err)
+
+	bearerToken := fmt.Sprintf("%s %s", auth.AuthorizationTypeBearer, accessToken)
+	md := metadata.MD{
+		authorizationHeader: []string{bearerToken},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+// buildSessionStub makes the auth interceptor find an active session of the user
+func buildSessionStub(store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		GetAuthSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.GetAuthSessionRow{Session: db.Session{
+			Username:  username,
+			ExpiresAt: time.Now().Add(time.Minute),
+		}}, nil)
+}
+
+func randomUser(t *testing.T) (user db.User, password string) {
+	password = util.RandomString(6)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+
+	user = db.User{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+		Role:           util.DepositorRole,
+	}
+	return
+}
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+	}
+}
@@ -0,0 +1,44 @@
+package gapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	userAgentHeader            = "user-agent"
+	xForwardedForHeader        = "x-forwarded-for"
+	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
+)
+
+type clientMetadata struct {
+	UserAgent string
+	ClientIP  string
+}
+
+// extractMetadata finds the user agent & IP of the client, which may come thru a gateway
+func extractMetadata(ctx context.Context) clientMetadata {
+	mtdt := clientMetadata{}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		} else if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		}
+
+		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
+			mtdt.ClientIP = clientIPs[0]
+		}
+	}
+
+	if len(mtdt.ClientIP) == 0 {
+		if p, ok := peer.FromContext(ctx); ok {
+			mtdt.ClientIP = p.Addr.String()
+		}
+	}
+
+	return mtdt
+}
@@ -0,0 +1,132 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// checkLoginRateLimit does for gRPC what the rate limit middleware of the login route does for Gin:
+// both the client IP & the username may only try to log in so often
+func (server *Server) checkLoginRateLimit(ctx context.Context, username string) error {
+	buckets := []struct {
+		name   string
+		logins int
+		key    string
+	}{
+		{"login_ip", server.config.LoginRateLimitPerIP, server.rateLimitIP(ctx)},
+		{"login_username", server.config.LoginRateLimitPerUsername, username},
+	}
+
+	for _, bucket := range buckets {
+		limit := ratelimit.Limit{Burst: bucket.logins, Interval: server.config.LoginRateLimitInterval}
+		if limit.Unlimited() || bucket.key == "" {
+			continue
+		}
+
+		result, err := server.rateLimiter.Take(ctx, bucket.name+":"+bucket.key, limit)
+		if err != nil {
+			// Locking everyone out because the limiter is down would be worse than not limiting for a while
+			zerolog.Ctx(ctx).Error().Err(err).Str("limit", bucket.name).Msg("cannot take from rate limit bucket")
+			continue
+		}
+
+		if !result.Allowed {
+			return status.Errorf(codes.ResourceExhausted, "too many requests, try again in %s", result.RetryAfter.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// rateLimitIP is the IP of the client the limit applies to. Unlike the one of extractMetadata, it can't be made up:
+// X-Forwarded-For is read the way Gin reads it, only when the peer is a trusted proxy, and from the last hop back to
+// the first IP which isn't one. The gateway, which runs next to the server & adds the IP it saw, is always trusted.
+// The port is dropped, so a client can't get a new bucket by opening a new connection.
+func (server *Server) rateLimitIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	if !server.isTrustedProxy(net.ParseIP(ip)) {
+		return ip
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ip
+	}
+	forwarded := md.Get(xForwardedForHeader)
+	if len(forwarded) == 0 {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		hopIP := net.ParseIP(hop)
+		if hopIP == nil {
+			break
+		}
+		if i == 0 || !server.isTrustedProxy(hopIP) {
+			return hop
+		}
+	}
+	return ip
+}
+
+func (server *Server) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+
+	for _, proxy := range server.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies reads the IPs or CIDRs of the trusted proxies, like Gin does
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", proxy)
+			}
+
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
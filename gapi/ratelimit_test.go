@@ -0,0 +1,98 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRateLimitIP(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	require.NoError(t, err)
+	server := &Server{trustedProxies: trustedProxies}
+
+	testCases := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		ip        string
+	}{
+		{
+			name: "Peer",
+			peer: "203.0.113.7:51234",
+			ip:   "203.0.113.7",
+		},
+		{
+			name:      "ForwardedToClient",
+			peer:      "203.0.113.7:51234",
+			forwarded: []string{"198.51.100.1"},
+			ip:        "203.0.113.7",
+		},
+		{
+			name:      "Gateway",
+			peer:      "127.0.0.1:51234",
+			forwarded: []string{"198.51.100.1"},
+			ip:        "198.51.100.1",
+		},
+		{
+			name:      "GatewayMadeUpHeader",
+			peer:      "127.0.0.1:51234",
+			forwarded: []string{"192.0.2.1, 198.51.100.1"},
+			ip:        "198.51.100.1",
+		},
+		{
+			// The ingress in front of the gateway is trusted, so its clients don't share one bucket
+			name:      "GatewayBehindTrustedProxy",
+			peer:      "127.0.0.1:51234",
+			forwarded: []string{"192.0.2.1, 198.51.100.1, 10.1.2.3"},
+			ip:        "198.51.100.1",
+		},
+		{
+			name:      "GatewayBehindUntrustedProxy",
+			peer:      "127.0.0.1:51234",
+			forwarded: []string{"198.51.100.1, 203.0.113.9"},
+			ip:        "203.0.113.9",
+		},
+		{
+			name:      "TrustedProxy",
+			peer:      "192.0.2.10:51234",
+			forwarded: []string{"198.51.100.1"},
+			ip:        "198.51.100.1",
+		},
+		{
+			name:      "OnlyTrustedProxies",
+			peer:      "127.0.0.1:51234",
+			forwarded: []string{"10.0.0.1, 10.1.2.3"},
+			ip:        "10.0.0.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tc.peer)
+			require.NoError(t, err)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+			if tc.forwarded != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{xForwardedForHeader: tc.forwarded})
+			}
+			require.Equal(t, tc.ip, server.rateLimitIP(ctx))
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10", "2001:db8::1"})
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	require.True(t, proxies[1].Contains(net.ParseIP("192.0.2.10")))
+	require.False(t, proxies[1].Contains(net.ParseIP("192.0.2.11")))
+	require.True(t, proxies[2].Contains(net.ParseIP("2001:db8::1")))
+
+	_, err = parseTrustedProxies([]string{"not-an-ip"})
+	require.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/kvnyijia/bank-app/auth"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pagination"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultPageSize = 20
+
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		return nil, invalidArgumentError("currency", err)
+	}
+
+	account, err := server.store.CreateAccount(ctx, db.CreateAccountParams{
+		Owner:    payload.Username,
+		Currency: req.GetCurrency(),
+		Balance:  0,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation", "unique_violation":
+				return nil, status.Errorf(codes.AlreadyExists, "cannot create account: %s", err)
+			}
+		}
+		return nil, status.Errorf(codes.Internal, "cannot create account: %s", err)
+	}
+
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateID(req.GetId()); err != nil {
+		return nil, invalidArgumentError("id", err)
+	}
+
+	account, err := server.getAccount(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if !auth.CanRead(payload, account.Owner) {
+		return nil, status.Error(codes.PermissionDenied, "account doesn't belong to the authenticated user")
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePageSize(req.GetPageSize()); err != nil {
+		return nil, invalidArgumentError("page_size", err)
+	}
+
+	limit := req.GetPageSize()
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	// Same scope as the HTTP listing, so page tokens work on both
+	scope := "accounts:" + payload.Username
+	after, err := server.cursorSigner.Decode(req.GetPageToken(), scope)
+	if err != nil {
+		return nil, invalidArgumentError("page_token", err)
+	}
+
+	accounts, err := server.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:          payload.Username,
+		AfterCreatedAt: after.NullCreatedAt(),
+		AfterID:        after.NullID(),
+		Limit:          limit + 1,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list accounts: %s", err)
+	}
+
+	accounts, next, err := pagination.Paginate(server.cursorSigner, accounts, limit, scope, func(account db.Account) (time.Time, int64) {
+		return account.CreatedAt, account.ID
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot create page token: %s", err)
+	}
+
+	rsp := &pb.ListAccountsResponse{
+		Accounts:      make([]*pb.Account, len(accounts)),
+		NextPageToken: next,
+	}
+	for i, account := range accounts {
+		rsp.Accounts[i] = convertAccount(account)
+	}
+	return rsp, nil
+}
+
+// getAccount gets the account, or returns the status error
+func (server *Server) getAccount(ctx context.Context, id int64) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, status.Errorf(codes.NotFound, "account [%d] not found", id)
+		}
+		return account, status.Errorf(codes.Internal, "cannot get account: %s", err)
+	}
+	return account, nil
+}
@@ -0,0 +1,198 @@
+package gapi
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	buildSessionStub(store, user.Username)
+	buildSessionStub(store, user.Username)
+
+	arg := db.CreateAccountParams{
+		Owner:    user.Username,
+		Currency: account.Currency,
+	}
+	store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, util.DepositorRole, time.Minute)
+
+	rsp, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Currency: account.Currency})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, rsp.GetAccount().GetId())
+	require.Equal(t, user.Username, rsp.GetAccount().GetOwner())
+
+	_, err = client.CreateAccount(ctx, &pb.CreateAccountRequest{Currency: "XYZ"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name         string
+		id           int64
+		username     string
+		role         string
+		buildStubs   func(store *mockdb.MockStore)
+		expectedCode codes.Code
+	}{
+		{
+			name:     "OK",
+			id:       account.ID,
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:     "BankerViewsOtherAccount",
+			id:       account.ID,
+			username: "banker",
+			role:     util.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:     "UnauthorizedUser",
+			id:       account.ID,
+			username: "unauthorized_user",
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:     "NotFound",
+			id:       account.ID,
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:     "InternalError",
+			id:       account.ID,
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrConnDone)
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name:     "InvalidID",
+			id:       0,
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildSessionStub(store, tc.username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.username, tc.role, time.Minute)
+
+			rsp, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: tc.id})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedCode == codes.OK {
+				require.Equal(t, account.ID, rsp.GetAccount().GetId())
+				require.Equal(t, account.Balance, rsp.GetAccount().GetBalance())
+			}
+		})
+	}
+}
+
+func TestListAccountsRPC(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 3
+	accounts := make([]db.Account, n)
+	for i := range accounts {
+		accounts[i] = randomAccount(user.Username)
+		accounts[i].CreatedAt = time.Now().UTC().Truncate(time.Microsecond).Add(time.Duration(i) * time.Second)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAuthSession(gomock.Any(), gomock.Any()).AnyTimes().Return(db.GetAuthSessionRow{Session: db.Session{
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}}, nil)
+
+	firstPage := db.ListAccountsParams{
+		Owner: user.Username,
+		Limit: 3,
+	}
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(firstPage)).Times(1).Return(accounts, nil)
+
+	secondPage := db.ListAccountsParams{
+		Owner:          user.Username,
+		AfterCreatedAt: sql.NullTime{Time: accounts[1].CreatedAt, Valid: true},
+		AfterID:        sql.NullInt64{Int64: accounts[1].ID, Valid: true},
+		Limit:          3,
+	}
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(secondPage)).Times(1).Return(accounts[2:], nil)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, util.DepositorRole, time.Minute)
+
+	rsp, err := client.ListAccounts(ctx, &pb.ListAccountsRequest{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, rsp.GetAccounts(), 2)
+	require.NotEmpty(t, rsp.GetNextPageToken())
+
+	rsp, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageSize: 2, PageToken: rsp.GetNextPageToken()})
+	require.NoError(t, err)
+	require.Len(t, rsp.GetAccounts(), 1)
+	require.Equal(t, accounts[2].ID, rsp.GetAccounts()[0].GetId())
+	require.Empty(t, rsp.GetNextPageToken())
+
+	_, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageToken: "invalid"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageSize: 101})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
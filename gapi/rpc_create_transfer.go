@@ -0,0 +1,131 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/fx"
+	"github.com/kvnyijia/bank-app/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateTransferRequest(req); err != nil {
+		return nil, err
+	}
+
+	if err := server.checkVerifiedEmail(ctx, payload.Username); err != nil {
+		return nil, err
+	}
+
+	if err := server.checkStepUp(payload, req.GetCurrency(), req.GetAmount()); err != nil {
+		return nil, err
+	}
+
+	fromAccount, err := server.getAccount(ctx, req.GetFromAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	// Nobody moves money out of someone else's account, whatever their role is
+	if fromAccount.Owner != payload.Username {
+		return nil, status.Error(codes.PermissionDenied, "from account doesn't belong to the authenticated user")
+	}
+
+	if fromAccount.Currency != req.GetCurrency() {
+		return nil, status.Errorf(codes.InvalidArgument, "account [%d] currency mismatch: %s vs %s", fromAccount.ID, fromAccount.Currency, req.GetCurrency())
+	}
+
+	toAccount, err := server.getAccount(ctx, req.GetToAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	var result db.TransferTxResult
+	if toAccount.Currency == fromAccount.Currency {
+		result, err = server.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        req.GetAmount(),
+		})
+	} else {
+		// The to account is credited in its own currency at the current rate
+		var rate fx.Rate
+		var toAmount int64
+		rate, toAmount, err = server.convert(ctx, fromAccount.Currency, toAccount.Currency, req.GetAmount())
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = server.store.ExchangeTransferTx(ctx, db.ExchangeTransferTxParams{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        req.GetAmount(),
+			ToAmount:      toAmount,
+			ExchangeRate:  rate.Value,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrAccountInactive) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "cannot transfer: %s", err)
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}
+	return rsp, nil
+}
+
+// convert looks up the current rate and converts the amount, or returns the status error
+func (server *Server) convert(ctx context.Context, from string, to string, amount int64) (fx.Rate, int64, error) {
+	if server.rateProvider == nil {
+		return fx.Rate{}, 0, status.Error(codes.Unavailable, "currency exchange is not available")
+	}
+
+	rate, err := server.rateProvider.GetRate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			return rate, 0, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return rate, 0, status.Errorf(codes.Unavailable, "cannot get exchange rate: %s", err)
+	}
+
+	toAmount, err := rate.Convert(amount)
+	if err != nil {
+		return rate, 0, status.Errorf(codes.Internal, "cannot convert amount: %s", err)
+	}
+
+	if toAmount <= 0 {
+		return rate, 0, status.Errorf(codes.InvalidArgument, "amount %d %s is too small to convert to %s", amount, from, to)
+	}
+	return rate, toAmount, nil
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) error {
+	if err := validateID(req.GetFromAccountId()); err != nil {
+		return invalidArgumentError("from_account_id", err)
+	}
+	if err := validateID(req.GetToAccountId()); err != nil {
+		return invalidArgumentError("to_account_id", err)
+	}
+	if err := validateAmount(req.GetAmount()); err != nil {
+		return invalidArgumentError("amount", err)
+	}
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		return invalidArgumentError("currency", err)
+	}
+	return nil
+}
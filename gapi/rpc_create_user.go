@@ -0,0 +1,60 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if err := validateCreateUserRequest(req); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := util.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot hash password: %s", err)
+	}
+
+	arg := db.CreateUserParams{
+		Username:       req.GetUsername(),
+		HashedPassword: hashedPassword,
+		FullName:       req.GetFullName(),
+		Email:          req.GetEmail(),
+	}
+
+	// The email to verify the address is sent by the email worker
+	result, err := server.store.CreateUserTx(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return nil, status.Errorf(codes.AlreadyExists, "user already exists: %s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "cannot create user: %s", err)
+	}
+
+	rsp := &pb.CreateUserResponse{
+		User: convertUser(result.User),
+	}
+	return rsp, nil
+}
+
+func validateCreateUserRequest(req *pb.CreateUserRequest) error {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		return invalidArgumentError("username", err)
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		return invalidArgumentError("password", err)
+	}
+	if err := validateFullName(req.GetFullName()); err != nil {
+		return invalidArgumentError("full_name", err)
+	}
+	if err := validateEmail(req.GetEmail()); err != nil {
+		return invalidArgumentError("email", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kvnyijia/bank-app/auth"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if err := validateLoginUserRequest(req); err != nil {
+		return nil, err
+	}
+
+	if err := server.checkLoginRateLimit(ctx, req.GetUsername()); err != nil {
+		return nil, err
+	}
+
+	// Unknown users, wrong passwords & locked users all get the same answer, after as long,
+	// so it doesn't tell which usernames exist
+	user, err := server.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			util.CheckPasswordOfMissingUser(req.GetPassword())
+			return nil, errInvalidCredentials
+		}
+		return nil, status.Errorf(codes.Internal, "cannot get user: %s", err)
+	}
+
+	if err := server.authChecker.CheckPassword(ctx, user, req.GetPassword()); err != nil {
+		return nil, credentialsError(err)
+	}
+
+	if user.TotpEnabled {
+		return server.mfaChallenge(user)
+	}
+
+	return server.createLogin(ctx, user, []string{token.AMRPassword})
+}
+
+// createLogin starts a session for the user, who proved who they are with the methods, and returns its tokens
+func (server *Server) createLogin(ctx context.Context, user db.User, amr []string) (*pb.LoginUserResponse, error) {
+	mtdt := extractMetadata(ctx)
+	login, err := server.sessions.CreateLogin(ctx, auth.CreateLoginParams{
+		User:      user,
+		AMR:       amr,
+		UserAgent: mtdt.UserAgent,
+		ClientIP:  mtdt.ClientIP,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+
+	rsp := &pb.LoginUserResponse{
+		User:                  convertUser(user),
+		SessionId:             login.Session.ID.String(),
+		AccessToken:           login.AccessToken,
+		RefreshToken:          login.RefreshToken,
+		AccessTokenExpiresAt:  timestamppb.New(login.AccessPayload.ExpiredAt),
+		RefreshTokenExpiresAt: timestamppb.New(login.RefreshPayload.ExpiredAt),
+	}
+	return rsp, nil
+}
+
+var errInvalidCredentials = status.Error(codes.Unauthenticated, "invalid credentials")
+
+// credentialsError is the status of credentials which didn't check out, or couldn't be checked
+func credentialsError(err error) error {
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return errInvalidCredentials
+	}
+	return status.Errorf(codes.Internal, "cannot check credentials: %s", err)
+}
+
+func validateLoginUserRequest(req *pb.LoginUserRequest) error {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		return invalidArgumentError("username", err)
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		return invalidArgumentError("password", err)
+	}
+	return nil
+}
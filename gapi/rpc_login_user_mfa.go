@@ -0,0 +1,90 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LoginUserMFA finishes the login of a user with TOTP on, once the second factor checks out
+func (server *Server) LoginUserMFA(ctx context.Context, req *pb.LoginUserMFARequest) (*pb.LoginUserResponse, error) {
+	if err := validateLoginUserMFARequest(req); err != nil {
+		return nil, err
+	}
+
+	mfaPayload, err := server.tokenMaker.VerifyToken(req.GetMfaToken())
+	if err != nil {
+		metrics.ObserveTokenVerificationFailure(err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if mfaPayload.Purpose != token.PurposeMFAChallenge {
+		return nil, status.Error(codes.Unauthenticated, "token is not an MFA challenge")
+	}
+
+	if err := server.checkLoginRateLimit(ctx, mfaPayload.Username); err != nil {
+		return nil, err
+	}
+
+	user, err := server.store.GetUser(ctx, mfaPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errInvalidCredentials
+		}
+		return nil, status.Errorf(codes.Internal, "cannot get user: %s", err)
+	}
+
+	// The password the challenge was issued for must still be the current one
+	if mfaPayload.IssuedAt.Before(user.PasswordChangedAt) {
+		return nil, status.Error(codes.Unauthenticated, "token was issued before the password was changed")
+	}
+
+	if err := server.authChecker.CheckCode(ctx, user, req.GetCode()); err != nil {
+		return nil, credentialsError(err)
+	}
+
+	// Each challenge is exchanged for one login at most
+	_, err = server.store.UseMFAChallenge(ctx, db.UseMFAChallengeParams{
+		Username: user.Username,
+		IssuedAt: mfaPayload.IssuedAt,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.Unauthenticated, "MFA challenge was used already")
+		}
+		return nil, status.Errorf(codes.Internal, "cannot use MFA challenge: %s", err)
+	}
+
+	return server.createLogin(ctx, user, []string{token.AMRPassword, token.AMROTP})
+}
+
+// mfaChallenge answers a login with the right password of a user with TOTP on
+func (server *Server) mfaChallenge(user db.User) (*pb.LoginUserResponse, error) {
+	mfaToken, mfaPayload, err := server.sessions.CreateMFAChallenge(user)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+
+	rsp := &pb.LoginUserResponse{
+		MfaRequired:       true,
+		MfaToken:          mfaToken,
+		MfaTokenExpiresAt: timestamppb.New(mfaPayload.ExpiredAt),
+	}
+	return rsp, nil
+}
+
+func validateLoginUserMFARequest(req *pb.LoginUserMFARequest) error {
+	if err := validateRequired(req.GetMfaToken()); err != nil {
+		return invalidArgumentError("mfa_token", err)
+	}
+	if err := validateRequired(req.GetCode()); err != nil {
+		return invalidArgumentError("code", err)
+	}
+	return nil
+}
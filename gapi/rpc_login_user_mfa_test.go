@@ -0,0 +1,211 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoginUserMFARPC(t *testing.T) {
+	user, _ := randomUser(t)
+	user.TotpEnabled = true
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	wrongCode := ""
+	for i := 0; wrongCode == ""; i++ {
+		if _, ok := totp.Validate(secret, fmt.Sprintf("%06d", i), time.Now()); !ok {
+			wrongCode = fmt.Sprintf("%06d", i)
+		}
+	}
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		purpose       string
+		code          string
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error)
+	}{
+		{
+			name:    "OK",
+			user:    user,
+			purpose: token.PurposeMFAChallenge,
+			code:    code,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseMFAChallengeParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now(), arg.IssuedAt, time.Second)
+						return user, nil
+					})
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.False(t, rsp.GetMfaRequired())
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetAccessToken())
+				require.NoError(t, err)
+				require.Empty(t, payload.Purpose)
+				require.Equal(t, rsp.GetSessionId(), payload.SessionID.String())
+			},
+		},
+		{
+			name:    "RecoveryCode",
+			user:    user,
+			purpose: token.PurposeMFAChallenge,
+			code:    "ABCDE-FGHIJ",
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username: user.Username,
+						CodeHash: util.HashSecureToken("abcdefghij"),
+					})).
+					Times(1).
+					Return(db.RecoveryCode{Username: user.Username}, nil)
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:    "UsedChallenge",
+			user:    user,
+			purpose: token.PurposeMFAChallenge,
+			code:    code,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name:    "WrongCode",
+			user:    user,
+			purpose: token.PurposeMFAChallenge,
+			code:    wrongCode,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name:    "ReplayedCode",
+			user:    user,
+			purpose: token.PurposeMFAChallenge,
+			code:    code,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name:    "LockedUser",
+			user:    lockedUser,
+			purpose: token.PurposeMFAChallenge,
+			code:    code,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name: "AccessToken",
+			user: user,
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name:    "NoCode",
+			user:    user,
+			purpose: token.PurposeMFAChallenge,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+
+			user := tc.user
+			user.TotpSecret, err = server.totpCipher.Seal(secret)
+			require.NoError(t, err)
+			tc.buildStubs(store, user)
+
+			mfaToken, _, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+				Username: user.Username,
+				Role:     user.Role,
+				Duration: time.Minute,
+				Purpose:  tc.purpose,
+			})
+			require.NoError(t, err)
+
+			rsp, err := client.LoginUserMFA(context.Background(), &pb.LoginUserMFARequest{MfaToken: mfaToken, Code: tc.code})
+			tc.checkResponse(t, server, rsp, err)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoginUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+
+				// The access token belongs to the session which was created
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetAccessToken())
+				require.NoError(t, err)
+				require.Equal(t, rsp.GetSessionId(), payload.SessionID.String())
+			},
+		},
+		{
+			name: "UserNotFound",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					RecordFailedLogin(gomock.Any(), gomock.Eq(db.RecordFailedLoginParams{
+						Username:          user.Username,
+						MaxAttempts:       3,
+						LockoutSeconds:    60,
+						MaxLockoutSeconds: 3600,
+					})).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name: "LockedUser",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				lockedUser := user
+				lockedUser.FailedLoginAttempts = 3
+				lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(lockedUser, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name: "MFARequired",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				totpUser := user
+				totpUser.TotpEnabled = true
+				totpUser.FailedLoginAttempts = 2
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(totpUser, nil)
+				store.EXPECT().ResetFailedLogins(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.GetMfaRequired())
+				require.Empty(t, rsp.GetAccessToken())
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetMfaToken())
+				require.NoError(t, err)
+				require.Equal(t, token.PurposeMFAChallenge, payload.Purpose)
+				require.Equal(t, user.Username, payload.Username)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			req:  &pb.LoginUserRequest{Username: "invalid-user#1", Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+
+			rsp, err := client.LoginUser(context.Background(), tc.req)
+			tc.checkResponse(t, server, rsp, err)
+		})
+	}
+}
+
+func TestLoginUserRPCRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(db.User{}, sql.ErrNoRows)
+
+	server := newTestServer(t, store)
+	server.config.LoginRateLimitPerUsername = 2
+	server.config.LoginRateLimitInterval = time.Minute
+	client := newTestClient(t, server)
+
+	req := &pb.LoginUserRequest{Username: util.RandomOwner(), Password: util.RandomString(6)}
+	for i := 0; i < 2; i++ {
+		_, err := client.LoginUser(context.Background(), req)
+		requireInvalidCredentials(t, err)
+	}
+
+	_, err := client.LoginUser(context.Background(), req)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// requireInvalidCredentials checks the answer every failed login gets, whatever the reason
+func requireInvalidCredentials(t *testing.T, err error) {
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, "invalid credentials", status.Convert(err).Message())
+}
@@ -0,0 +1,55 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kvnyijia/bank-app/auth"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StepUp re-authenticates the user, and gives them an access token of the same session which counts as a
+// recent authentication
+func (server *Server) StepUp(ctx context.Context, req *pb.StepUpRequest) (*pb.StepUpResponse, error) {
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot get user: %s", err)
+	}
+
+	// A stolen token must not be enough to guess the password or codes, so wrong ones count like failed logins
+	amr, err := server.authChecker.StepUp(ctx, user, req.GetPassword(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrStepUpMethod) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, credentialsError(err)
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(token.CreateTokenParams{
+		Username:  payload.Username,
+		Role:      payload.Role,
+		SessionID: payload.SessionID,
+		Duration:  server.config.AccessTokenDuration,
+		AuthTime:  time.Now(),
+		AMR:       amr,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot create access token: %s", err)
+	}
+
+	rsp := &pb.StepUpResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: timestamppb.New(accessPayload.ExpiredAt),
+	}
+	return rsp, nil
+}
@@ -0,0 +1,146 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStepUpRPC(t *testing.T) {
+	user, password := randomUser(t)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	totpUser := user
+	totpUser.TotpEnabled = true
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		req           *pb.StepUpRequest
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(t *testing.T, server *Server, rsp *pb.StepUpResponse, err error)
+	}{
+		{
+			name: "Password",
+			user: user,
+			req:  &pb.StepUpRequest{Password: password},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.StepUpResponse, err error) {
+				require.NoError(t, err)
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetAccessToken())
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now(), payload.AuthTime, time.Second)
+				require.Equal(t, []string{token.AMRPassword}, payload.AMR)
+			},
+		},
+		{
+			name: "TOTPCode",
+			user: totpUser,
+			req:  &pb.StepUpRequest{Code: code},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.StepUpResponse, err error) {
+				require.NoError(t, err)
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetAccessToken())
+				require.NoError(t, err)
+				require.Equal(t, []string{token.AMROTP}, payload.AMR)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			user: user,
+			req:  &pb.StepUpRequest{Password: "incorrect"},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.StepUpResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+		{
+			name: "PasswordOfTOTPUser",
+			user: totpUser,
+			req:  &pb.StepUpRequest{Password: password},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.StepUpResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "LockedUser",
+			user: lockedUser,
+			req:  &pb.StepUpRequest{Password: password},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.StepUpResponse, err error) {
+				requireInvalidCredentials(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+
+			user := tc.user
+			user.TotpSecret, err = server.totpCipher.Seal(secret)
+			require.NoError(t, err)
+
+			buildSessionStub(store, user.Username)
+			tc.buildStubs(store, user)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, util.DepositorRole, time.Minute)
+			rsp, err := client.StepUp(ctx, tc.req)
+			tc.checkResponse(t, server, rsp, err)
+		})
+	}
+}
+
+func TestStepUpRPCNoAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+
+	_, err := client.StepUp(context.Background(), &pb.StepUpRequest{Password: "secret"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,100 @@
+package gapi
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/kvnyijia/bank-app/auth"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/fx"
+	"github.com/kvnyijia/bank-app/pagination"
+	"github.com/kvnyijia/bank-app/pb"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/kvnyijia/bank-app/totp"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server serves gRPC reqs for our banking service
+type Server struct {
+	pb.UnimplementedBankServer
+	config       util.Config
+	store        db.Store
+	tokenMaker   token.Maker
+	rateProvider fx.RateProvider // nil when currency exchange is not configured
+	cursorSigner *pagination.CursorSigner
+	rateLimiter  ratelimit.Store
+	// Proxies whose X-Forwarded-For tells the client IP, besides the gateway
+	trustedProxies []*net.IPNet
+	totpCipher     *totp.Cipher
+	authChecker    *auth.Checker
+	sessions       *auth.Sessions
+	// Transfers of at least these amounts per currency need a recent authentication
+	stepUpThresholds map[string]int64
+	logger           zerolog.Logger
+}
+
+// Creates a new gRPC server on top of the same store, tokens & rate limit buckets as the HTTP one
+func NewServer(config util.Config, store db.Store, rateLimiter ratelimit.Store, logger zerolog.Logger) (*Server, error) {
+	tokenMaker, err := token.NewMakerFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	rateProvider, err := fx.NewRateProviderFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate provider: %w", err)
+	}
+
+	cursorSigner, err := pagination.NewCursorSigner(config.CursorSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create cursor signer: %w", err)
+	}
+
+	totpCipher, err := totp.NewCipher(config.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TOTP cipher: %w", err)
+	}
+
+	stepUpThresholds, err := util.ParseCurrencyAmounts(config.StepUpThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step-up thresholds: %w", err)
+	}
+
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	server := &Server{
+		config:           config,
+		store:            store,
+		tokenMaker:       tokenMaker,
+		rateProvider:     rateProvider,
+		cursorSigner:     cursorSigner,
+		rateLimiter:      rateLimiter,
+		trustedProxies:   trustedProxies,
+		totpCipher:       totpCipher,
+		authChecker:      auth.NewChecker(config, store, totpCipher),
+		sessions:         auth.NewSessions(config, store, tokenMaker),
+		stepUpThresholds: stepUpThresholds,
+		logger:           logger,
+	}
+	return server, nil
+}
+
+// NewGRPCServer registers the service on a gRPC server which traces, logs & authenticates every call first
+func (server *Server) NewGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		otelgrpc.UnaryServerInterceptor(),
+		server.loggerInterceptor,
+		server.authInterceptor,
+	))
+	pb.RegisterBankServer(grpcServer, server)
+	reflection.Register(grpcServer) // Lets clients like grpcurl discover the service
+	return grpcServer
+}
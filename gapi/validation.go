@@ -0,0 +1,82 @@
+package gapi
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+
+	"github.com/kvnyijia/bank-app/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The same rules as the binding tags of the HTTP API
+
+var isAlphanum = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
+
+func invalidArgumentError(field string, err error) error {
+	return status.Errorf(codes.InvalidArgument, "invalid %s: %s", field, err)
+}
+
+func validateUsername(value string) error {
+	if !isAlphanum(value) {
+		return fmt.Errorf("must contain only letters and digits")
+	}
+	return nil
+}
+
+func validatePassword(value string) error {
+	if len(value) < 6 {
+		return fmt.Errorf("must contain at least 6 characters")
+	}
+	return nil
+}
+
+func validateFullName(value string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+func validateEmail(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("is not a valid email address")
+	}
+	return nil
+}
+
+func validateCurrency(value string) error {
+	if !util.IsSupportedCurrency(value) {
+		return fmt.Errorf("unsupported currency %q", value)
+	}
+	return nil
+}
+
+func validateID(value int64) error {
+	if value < 1 {
+		return fmt.Errorf("must be at least 1")
+	}
+	return nil
+}
+
+func validateAmount(value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+	return nil
+}
+
+func validatePageSize(value int32) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("must be between 1 and 100")
+	}
+	return nil
+}
+
+func validateRequired(value string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package logging builds the structured logger shared by the servers & the store
+package logging
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields every request log has
+const (
+	RequestIDField = "request_id"
+	UsernameField  = "username"
+	TraceIDField   = "trace_id"
+)
+
+// New creates a JSON logger at the level of the config, or a human readable one if LOG_FORMAT is console
+func New(config util.Config, out io.Writer) (zerolog.Logger, error) {
+	level := zerolog.InfoLevel
+	if config.LogLevel != "" {
+		var err error
+		level, err = zerolog.ParseLevel(config.LogLevel)
+		if err != nil {
+			return zerolog.Logger{}, err
+		}
+	}
+
+	if config.LogFormat == "console" {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	}
+	return zerolog.New(out).Level(level).With().Timestamp().Logger(), nil
+}
+
+// ForRequest tags the logger with the request ID, and with the trace ID if the req is traced,
+// so its logs can be found from its spans
+func ForRequest(ctx context.Context, logger zerolog.Logger, requestID string) zerolog.Logger {
+	logCtx := logger.With().Str(RequestIDField, requestID)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		logCtx = logCtx.Str(TraceIDField, spanContext.TraceID().String())
+	}
+	return logCtx.Logger()
+}
+
+// WithUsername adds the username to the logger of the req ctx, so every later log of the req has it
+func WithUsername(ctx context.Context, username string) {
+	zerolog.Ctx(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str(UsernameField, username)
+	})
+}
+
+// Request IDs from clients end up in our logs, so only short & plain ones are kept
+var isValidRequestID = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`).MatchString
+
+// RequestID keeps the request ID the client sent, or makes a new one if there is none or it can't be trusted
+func RequestID(value string) string {
+	if isValidRequestID(value) {
+		return value
+	}
+	return uuid.NewString()
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Fields whose name contains any of these never make it into the logs
+var sensitiveFields = []string{"password", "token", "secret", "code"}
+
+// RedactJSON replaces the values of sensitive fields in a JSON body, however deep they are.
+// It returns false if the body is not JSON, in which case nothing of it should be logged.
+func RedactJSON(body []byte) (json.RawMessage, bool) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, false
+	}
+
+	data, err := json.Marshal(redact(value))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func redact(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if isSensitive(key) {
+				value[key] = redacted
+			} else {
+				value[key] = redact(field)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = redact(item)
+		}
+	}
+	return value
+}
+
+func isSensitive(field string) bool {
+	field = strings.ToLower(field)
+	for _, sensitive := range sensitiveFields {
+		if strings.Contains(field, sensitive) {
+			return true
+		}
+	}
+	return false
+}
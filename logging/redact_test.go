@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactJSON(t *testing.T) {
+	body := []byte(`{
+		"username": "alice",
+		"password": "secret123",
+		"session": {"refresh_token": "v2.local.abc", "id": 1},
+		"items": [{"new_password": "x"}, 42],
+		"mfa": {"code": "123456"}
+	}`)
+
+	redactedBody, ok := RedactJSON(body)
+	require.True(t, ok)
+	require.JSONEq(t, `{
+		"username": "alice",
+		"password": "[REDACTED]",
+		"session": {"refresh_token": "[REDACTED]", "id": 1},
+		"items": [{"new_password": "[REDACTED]"}, 42],
+		"mfa": {"code": "[REDACTED]"}
+	}`, string(redactedBody))
+
+	_, ok = RedactJSON([]byte("password=secret123"))
+	require.False(t, ok)
+}
+
+func TestRequestID(t *testing.T) {
+	require.Equal(t, "abc-123", RequestID("abc-123"))
+
+	for _, value := range []string{"", "has space", "line\nbreak", string(make([]byte, 129))} {
+		requestID := RequestID(value)
+		require.NotEqual(t, value, requestID)
+		require.Len(t, requestID, 36)
+	}
+}
@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// FileMailer writes every email to a .eml file in its dir, which mail clients can open
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail dir is not set")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create mail dir: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Only these chars of the recipient are kept in file names
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9.@_-]`)
+
+func (mailer *FileMailer) Send(ctx context.Context, email Email) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileNameChars.ReplaceAllString(email.To, "_"))
+	path := filepath.Join(mailer.dir, name)
+
+	if err := os.WriteFile(path, message(mailer.from, email), 0o600); err != nil {
+		return fmt.Errorf("cannot write email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// LogMailer only logs emails, bodies included, so it must not be used where the logs aren't private
+type LogMailer struct {
+	logger zerolog.Logger
+}
+
+func NewLogMailer(logger zerolog.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (mailer *LogMailer) Send(ctx context.Context, email Email) error {
+	mailer.logger.Info().
+		Str("to", email.To).
+		Str("subject", email.Subject).
+		Str("body", email.Body).
+		Msg("email")
+	return nil
+}
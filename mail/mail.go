@@ -0,0 +1,41 @@
+// Package mail sends emails to users, e.g. links to reset their password.
+// Emails go out via SMTP, or are only written to files or logs for local use.
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+)
+
+// Mailers emails can be sent with
+const (
+	MailerLog  = "log"
+	MailerFile = "file"
+	MailerSMTP = "smtp"
+)
+
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// New creates the mailer of the config, which logs emails if no mailer is set
+func New(config util.Config, logger zerolog.Logger) (Mailer, error) {
+	switch config.Mailer {
+	case "", MailerLog:
+		return NewLogMailer(logger), nil
+	case MailerFile:
+		return NewFileMailer(config.MailDir, config.MailFrom)
+	case MailerSMTP:
+		return NewSMTPMailer(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	}
+	return nil, fmt.Errorf("unsupported mailer %q", config.Mailer)
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, config := range []util.Config{
+		{},
+		{Mailer: MailerLog},
+		{Mailer: MailerFile, MailDir: t.TempDir()},
+		{Mailer: MailerSMTP, SMTPAddress: "localhost:25", MailFrom: "bank@example.com"},
+	} {
+		mailer, err := New(config, zerolog.Nop())
+		require.NoError(t, err)
+		require.NotNil(t, mailer)
+	}
+
+	for _, config := range []util.Config{
+		{Mailer: "pigeon"},
+		{Mailer: MailerFile},
+		{Mailer: MailerSMTP, SMTPAddress: "localhost"},
+		{Mailer: MailerSMTP, SMTPAddress: "localhost:25"},
+	} {
+		_, err := New(config, zerolog.Nop())
+		require.Error(t, err)
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var logs bytes.Buffer
+	mailer := NewLogMailer(zerolog.New(&logs))
+
+	err := mailer.Send(context.Background(), Email{To: "user@example.com", Subject: "Hi", Body: "Hello"})
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), `"to":"user@example.com"`)
+	require.Contains(t, logs.String(), `"body":"Hello"`)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer(dir, "bank@example.com")
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Email{
+		To:      "../user@example.com",
+		Subject: "Reset your password",
+		Body:    "Open the link:\nhttps://example.com",
+	})
+	require.NoError(t, err)
+
+	// The file stays in the dir whatever the recipient is
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.True(t, strings.HasSuffix(files[0].Name(), "-.._user@example.com.eml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "From: bank@example.com\r\n")
+	require.Contains(t, string(content), "Subject: Reset your password\r\n")
+	require.Contains(t, string(content), "\r\n\r\nOpen the link:\r\nhttps://example.com")
+}
+
+func TestMessageHeaderInjection(t *testing.T) {
+	msg := string(message("bank@example.com", Email{
+		To:      "user@example.com\r\nBcc: attacker@example.com",
+		Subject: "Hi",
+	}))
+	require.NotContains(t, msg, "\r\nBcc:")
+}
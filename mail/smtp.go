@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends emails thru an SMTP server, logging in with PLAIN auth if a username is set
+type SMTPMailer struct {
+	address string
+	auth    smtp.Auth
+	from    string
+}
+
+func NewSMTPMailer(address string, username string, password string, from string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address: %w", err)
+	}
+	if from == "" {
+		return nil, fmt.Errorf("mail from address is not set")
+	}
+
+	mailer := &SMTPMailer{address: address, from: from}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return mailer, nil
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, email Email) error {
+	// net/smtp can't be cancelled, so the email is sent in the background & the caller stops waiting once ctx is done
+	errs := make(chan error, 1)
+	go func() {
+		errs <- smtp.SendMail(mailer.address, mailer.auth, mailer.from, []string{email.To}, message(mailer.from, email))
+	}()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			return fmt.Errorf("cannot send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message formats the email as a plain text message, with the headers mail servers & clients expect
+func message(from string, email Email) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&sb, "To: %s\r\n", headerValue(email.To))
+	fmt.Fprintf(&sb, "Subject: %s\r\n", headerValue(email.Subject))
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(email.Body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
+
+// headerValue drops line breaks, so values can't add headers of their own
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -1,34 +1,289 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/kvnyijia/bank-app/api"
 	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/gapi"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/mail"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/ratelimit"
+	"github.com/kvnyijia/bank-app/tracing"
 	"github.com/kvnyijia/bank-app/util"
+	"github.com/kvnyijia/bank-app/worker"
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
+// Signals which tell the app to stop, e.g. k8s sends SIGTERM before killing a pod during a rollout
+var interruptSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
 func main() {
 	config, err := util.LoadConfig(".")
 	if err != nil {
-		log.Fatal("cannot load config:", err)
+		log.Fatal().Err(err).Msg("cannot load config")
+	}
+
+	// Every log from now on, including the ones of the servers & the store, goes thru the configured logger
+	log.Logger, err = logging.New(config, os.Stdout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create logger")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(config, os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("cannot run migrate command")
+		}
+		return
+	}
+
+	runDBMigration(config)
+
+	exporter, err := tracing.NewExporter(context.Background(), config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create trace exporter")
+	}
+	tracerProvider := tracing.NewProvider(exporter)
+	tracing.SetGlobal(tracerProvider)
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
-		log.Fatal("cannot connect to db:", err)
+		log.Fatal().Err(err).Msg("cannot connect to db")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
+	defer stop()
+
+	// Every server & background worker runs in the group. They all stop once one of them fails or a signal comes in.
+	waitGroup, ctx := errgroup.WithContext(ctx)
+
+	if err := metrics.RegisterDB(conn); err != nil {
+		log.Fatal().Err(err).Msg("cannot register db metrics")
 	}
 
-	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+	store := db.NewStore(conn, log.Logger, db.RetryPolicy{
+		MaxRetries: config.TxMaxRetries,
+		Backoff:    config.TxRetryBackoff,
+	})
+	// Both APIs take from the same buckets, so a caller can't get twice the logins by switching between them
+	rateLimiter := ratelimit.NewMemoryStore()
+	runGRPCServer(ctx, waitGroup, config, store, rateLimiter)
+	runGatewayServer(ctx, waitGroup, config)
+	runGinServer(ctx, waitGroup, config, store, rateLimiter)
+	runMetricsServer(ctx, waitGroup, config)
+	runEmailWorker(ctx, waitGroup, config, store)
+	runIdempotencyKeyCleaner(ctx, waitGroup, config, store)
+
+	err = waitGroup.Wait()
+
+	// Only close the db once no req can use it anymore
+	if closeErr := conn.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("cannot close db")
+	}
+
+	// Send the spans still in the batch before exiting
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if shutdownErr := tracerProvider.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Error().Err(shutdownErr).Msg("cannot shutdown tracer provider")
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("error from wait group")
+	}
+	log.Info().Msg("app stopped")
+}
+
+func runGRPCServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, rateLimiter ratelimit.Store) {
+	server, err := gapi.NewServer(config, store, rateLimiter, log.Logger)
 	if err != nil {
-		log.Fatal("cannot create server:", err)
+		log.Fatal().Err(err).Msg("cannot create gRPC server")
 	}
 
-	err = server.Start(config.ServerAddress)
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
 	if err != nil {
-		log.Fatal("cannot start server:", err)
+		log.Fatal().Err(err).Msg("cannot create listener")
 	}
+
+	grpcServer := server.NewGRPCServer()
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start gRPC server at %s", listener.Addr().String())
+		err := grpcServer.Serve(listener)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Error().Err(err).Msg("gRPC server failed to serve")
+			return err
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown gRPC server")
+
+		// GracefulStop has no deadline of its own, so calls still running after the timeout are cut off
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(config.ShutdownTimeout):
+			log.Warn().Msg("gRPC server did not stop in time, stop it forcefully")
+			grpcServer.Stop()
+		}
+
+		log.Info().Msg("gRPC server is stopped")
+		return nil
+	})
+}
+
+func runGatewayServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config) {
+	// The gateway is a client of our own gRPC server
+	conn, err := grpc.Dial(
+		config.GRPCServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()), // Calls continue the trace of the HTTP req
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot dial gRPC server")
+	}
+
+	handler, err := gapi.NewGatewayHandler(ctx, conn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create gateway")
+	}
+
+	httpServer := &http.Server{
+		Addr:         config.HTTPGatewayAddress,
+		Handler:      handler,
+		ReadTimeout:  config.HTTPReadTimeout,
+		WriteTimeout: config.HTTPWriteTimeout,
+		IdleTimeout:  config.HTTPIdleTimeout,
+	}
+
+	serveHTTP(ctx, waitGroup, "HTTP gateway server", httpServer, config.ShutdownTimeout, func() {
+		conn.Close()
+	})
+}
+
+func runGinServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, rateLimiter ratelimit.Store) {
+	server, err := api.NewServer(config, store, rateLimiter, log.Logger)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create server")
+	}
+
+	serveHTTP(ctx, waitGroup, "HTTP server", server.NewHTTPServer(config.ServerAddress), config.ShutdownTimeout, nil)
+}
+
+func runMetricsServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config) {
+	httpServer := &http.Server{
+		Addr:         config.MetricsAddress,
+		Handler:      metrics.Handler(),
+		ReadTimeout:  config.HTTPReadTimeout,
+		WriteTimeout: config.HTTPWriteTimeout,
+		IdleTimeout:  config.HTTPIdleTimeout,
+	}
+
+	serveHTTP(ctx, waitGroup, "metrics server", httpServer, config.ShutdownTimeout, nil)
+}
+
+func runEmailWorker(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	mailer, err := mail.New(config, log.Logger)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create mailer")
+	}
+
+	verifier := worker.NewEmailVerifier(config, store, mailer, log.Logger)
+	resetSender := worker.NewPasswordResetSender(config, store, mailer, log.Logger)
+
+	waitGroup.Go(func() error {
+		log.Info().Msg("start email worker")
+		err := verifier.Run(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("email worker failed")
+			return err
+		}
+		log.Info().Msg("email worker is stopped")
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		log.Info().Msg("start password reset worker")
+		err := resetSender.Run(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("password reset worker failed")
+			return err
+		}
+		log.Info().Msg("password reset worker is stopped")
+		return nil
+	})
+}
+
+func runIdempotencyKeyCleaner(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	cleaner := worker.NewIdempotencyKeyCleaner(config, store, log.Logger)
+
+	waitGroup.Go(func() error {
+		log.Info().Msg("start idempotency key cleaner")
+		err := cleaner.Run(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("idempotency key cleaner failed")
+			return err
+		}
+		log.Info().Msg("idempotency key cleaner is stopped")
+		return nil
+	})
+}
+
+// serveHTTP runs the server in the group until ctx is done, then lets in-flight reqs finish within the timeout.
+// cleanup, if any, runs after the server is shut down.
+func serveHTTP(ctx context.Context, waitGroup *errgroup.Group, name string, httpServer *http.Server, timeout time.Duration, cleanup func()) {
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start %s at %s", name, httpServer.Addr)
+		err := httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msgf("%s failed to serve", name)
+			return err
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msgf("graceful shutdown %s", name)
+
+		// ctx is already done, so the deadline has to come from a fresh one
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		err := httpServer.Shutdown(shutdownCtx)
+		if cleanup != nil {
+			cleanup()
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to shutdown %s", name)
+			return err
+		}
+
+		log.Info().Msgf("%s is stopped", name)
+		return nil
+	})
 }
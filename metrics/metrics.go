@@ -0,0 +1,121 @@
+// Package metrics holds the Prometheus collectors of the app, which are served on /metrics
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bank"
+
+// Route label of reqs which don't match any route, so random paths can't blow up the number of series
+const UnmatchedRoute = "unmatched"
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP reqs by route & status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	GRPCRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of gRPC calls by method & status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	Transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Transfer txs by result: created, or the reason they failed.",
+	}, []string{"result"})
+
+	TransferAmount = promauto.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  namespace,
+		Name:       "transfer_amount",
+		Help:       "Amounts of created transfers, in the currency of the from account.",
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+	}, []string{"currency"})
+
+	TransferTxConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_tx_conflicts_total",
+		Help:      "Transfer txs which failed because of a deadlock or a serialization failure, even after retries.",
+	}, []string{"code"})
+
+	TxAttempts = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tx_attempts",
+		Help:      "Attempts it took to run a db tx, whether it succeeded in the end or not.",
+		Buckets:   []float64{1, 2, 3, 4, 5, 8},
+	}, []string{"tx"})
+
+	TxRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tx_retries_total",
+		Help:      "Db txs run again after a deadlock or a serialization failure.",
+	}, []string{"tx", "code"})
+
+	VerificationEmails = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "verification_emails_total",
+		Help:      "Verification emails the email worker tried to send, by result.",
+	}, []string{"result"})
+
+	PasswordResetEmails = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "password_reset_emails_total",
+		Help:      "Password reset emails the email worker tried to send, by result.",
+	}, []string{"result"})
+
+	TokenVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_verification_failures_total",
+		Help:      "Tokens which failed verification, by reason.",
+	}, []string{"reason"})
+)
+
+// Results of transfer txs, besides the names of db conflicts
+const (
+	TransferCreated           = "created"
+	TransferInsufficientFunds = "insufficient_funds"
+	TransferAccountInactive   = "account_inactive"
+	TransferError             = "error"
+)
+
+// Results of sending verification & password reset emails
+const (
+	EmailSent   = "sent"
+	EmailFailed = "failed"
+	EmailNoUser = "no_user" // Nothing was sent, since no user has the email of a password reset
+)
+
+// ObserveTokenVerificationFailure counts a token which failed verification, telling expired tokens from invalid ones
+func ObserveTokenVerificationFailure(err error) {
+	reason := "invalid"
+	if errors.Is(err, token.ErrExpiredToken) {
+		reason = "expired"
+	}
+	TokenVerificationFailures.WithLabelValues(reason).Inc()
+}
+
+// RegisterDB exports the stats of the connection pool of the db
+func RegisterDB(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler serves all the metrics in the Prometheus text format
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kvnyijia/bank-app/token"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveTokenVerificationFailure(t *testing.T) {
+	expired := testutil.ToFloat64(TokenVerificationFailures.WithLabelValues("expired"))
+	invalid := testutil.ToFloat64(TokenVerificationFailures.WithLabelValues("invalid"))
+
+	ObserveTokenVerificationFailure(token.ErrExpiredToken)
+	ObserveTokenVerificationFailure(fmt.Errorf("wrapped: %w", token.ErrExpiredToken))
+	ObserveTokenVerificationFailure(token.ErrInvalidToken)
+
+	require.Equal(t, expired+2, testutil.ToFloat64(TokenVerificationFailures.WithLabelValues("expired")))
+	require.Equal(t, invalid+1, testutil.ToFloat64(TokenVerificationFailures.WithLabelValues("invalid")))
+}
+
+func TestHandler(t *testing.T) {
+	Transfers.WithLabelValues(TransferCreated).Inc()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	Handler().ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `bank_transfers_total{result="created"}`)
+	require.Contains(t, string(body), "go_goroutines")
+}
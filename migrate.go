@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/kvnyijia/bank-app/db/migration"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog/log"
+)
+
+const migrateUsage = "usage: bank-app migrate up [N] | down [N|all] | version"
+
+// runDBMigration brings the db up to date if auto migration is on, and refuses to serve a db the binary doesn't match
+func runDBMigration(config util.Config) {
+	m, err := migration.New(config.MigrationURL, config.DBSource)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create migrator")
+	}
+	defer m.Close()
+
+	if config.AutoMigrate {
+		if err := migration.Up(m); err != nil {
+			log.Fatal().Err(err).Msg("failed to run migrate up")
+		}
+		log.Info().Msg("db migrated successfully")
+	}
+
+	if err := migration.Check(m); err != nil {
+		log.Fatal().Err(err).Msg("refuse to serve the db")
+	}
+}
+
+// runMigrateCommand runs `bank-app migrate ...` instead of the servers
+func runMigrateCommand(config util.Config, args []string) error {
+	run, err := parseMigrateCommand(args)
+	if err != nil {
+		return err
+	}
+
+	m, err := migration.New(config.MigrationURL, config.DBSource)
+	if err != nil {
+		return fmt.Errorf("cannot create migrator: %w", err)
+	}
+	defer m.Close()
+
+	err = run(m)
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	version, dirty, err := migration.Version(m)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("db is at version %d (dirty: %t)", version, dirty)
+	return nil
+}
+
+// parseMigrateCommand checks the args before anything touches the db
+func parseMigrateCommand(args []string) (func(m *migrate.Migrate) error, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, errors.New(migrateUsage)
+	}
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		return (*migrate.Migrate).Up, nil
+	case args[0] == "down" && len(args) == 1:
+		return func(m *migrate.Migrate) error { return m.Steps(-1) }, nil
+	case args[0] == "down" && args[1] == "all":
+		// Rolling back everything wipes the data, so it has to be asked for explicitly
+		return (*migrate.Migrate).Down, nil
+	case args[0] == "up" || args[0] == "down":
+		steps, err := parseSteps(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if args[0] == "down" {
+			steps = -steps
+		}
+		return func(m *migrate.Migrate) error { return m.Steps(steps) }, nil
+	case args[0] == "version" && len(args) == 1:
+		return func(m *migrate.Migrate) error { return nil }, nil
+	}
+	return nil, errors.New(migrateUsage)
+}
+
+func parseSteps(arg string) (int, error) {
+	steps, err := strconv.Atoi(arg)
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("invalid number of migrations %q\n%s", arg, migrateUsage)
+	}
+	return steps, nil
+}
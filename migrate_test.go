@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMigrateCommand(t *testing.T) {
+	valid := [][]string{
+		{"up"},
+		{"up", "2"},
+		{"down"},
+		{"down", "3"},
+		{"down", "all"},
+		{"version"},
+	}
+	for _, args := range valid {
+		run, err := parseMigrateCommand(args)
+		require.NoError(t, err, args)
+		require.NotNil(t, run)
+	}
+
+	invalid := [][]string{
+		{},
+		{"sideways"},
+		{"up", "all"},
+		{"up", "0"},
+		{"down", "-1"},
+		{"version", "1"},
+		{"up", "1", "2"},
+	}
+	for _, args := range invalid {
+		_, err := parseMigrateCommand(args)
+		require.Error(t, err, args)
+	}
+}
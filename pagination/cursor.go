@@ -0,0 +1,123 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last row of a page, and the next page starts right after it.
+// Scope ties it to one listing, so it can't be replayed against another account.
+type Cursor struct {
+	Scope     string    `json:"s"`
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+// NullCreatedAt is the keyset position to pass to the db, which is null for the first page
+func (c *Cursor) NullCreatedAt() sql.NullTime {
+	if c == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}
+}
+
+func (c *Cursor) NullID() sql.NullInt64 {
+	if c == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: c.ID, Valid: true}
+}
+
+// CursorSigner makes cursors opaque & tamper-proof, so clients can't craft their own keyset positions
+type CursorSigner struct {
+	key []byte
+}
+
+const minKeySize = 32
+
+// NewCursorSigner signs cursors with a key of their own, which must be long enough that cursors can't be forged
+func NewCursorSigner(key string) (*CursorSigner, error) {
+	if len(key) < minKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minKeySize)
+	}
+	return &CursorSigner{key: []byte(key)}, nil
+}
+
+func (signer *CursorSigner) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, signer.key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (signer *CursorSigner) Encode(c Cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(data) + "." + encoding.EncodeToString(signer.sign(data)), nil
+}
+
+// Decode checks the cursor of a listing. An empty string is the first page, which gives a nil cursor.
+func (signer *CursorSigner) Decode(s string, scope string) (*Cursor, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	encodedData, encodedSignature, found := strings.Cut(s, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	encoding := base64.RawURLEncoding
+	data, err := encoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := encoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signer.sign(data)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	err = json.Unmarshal(data, &c)
+	if err != nil || c.Scope != scope {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Paginate trims a page fetched with one extra row, which only tells whether another page follows.
+// The returned cursor points at the last item kept, and is empty on the last page.
+func Paginate[T any](
+	signer *CursorSigner,
+	items []T,
+	limit int32,
+	scope string,
+	key func(item T) (time.Time, int64),
+) ([]T, string, error) {
+	if len(items) <= int(limit) {
+		return items, "", nil
+	}
+
+	items = items[:limit]
+	createdAt, id := key(items[limit-1])
+
+	next, err := signer.Encode(Cursor{
+		Scope:     scope,
+		CreatedAt: createdAt,
+		ID:        id,
+	})
+	return items, next, err
+}
@@ -0,0 +1,83 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner(t *testing.T) *CursorSigner {
+	signer, err := NewCursorSigner(util.RandomString(32))
+	require.NoError(t, err)
+	return signer
+}
+
+func TestCursorSigner(t *testing.T) {
+	signer := newTestSigner(t)
+
+	c := Cursor{
+		Scope:     "entries:1",
+		CreatedAt: time.Now().UTC(),
+		ID:        util.RandomInt(1, 1000),
+	}
+
+	s, err := signer.Encode(c)
+	require.NoError(t, err)
+
+	decoded, err := signer.Decode(s, c.Scope)
+	require.NoError(t, err)
+	require.Equal(t, c, *decoded)
+
+	// No cursor is the first page
+	decoded, err = signer.Decode("", c.Scope)
+	require.NoError(t, err)
+	require.Nil(t, decoded)
+
+	// A cursor of one listing can't be used for another
+	_, err = signer.Decode(s, "entries:2")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Nor can it be forged without the key
+	_, err = newTestSigner(t).Decode(s, c.Scope)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = signer.Decode("x"+s, c.Scope)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = signer.Decode("invalid", c.Scope)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestNewCursorSigner(t *testing.T) {
+	_, err := NewCursorSigner("")
+	require.Error(t, err)
+
+	_, err = NewCursorSigner(util.RandomString(31))
+	require.Error(t, err)
+}
+
+func TestPaginate(t *testing.T) {
+	signer := newTestSigner(t)
+	createdAt := time.Now().UTC()
+	key := func(id int64) (time.Time, int64) {
+		return createdAt, id
+	}
+
+	// A short page is the last one
+	items, next, err := Paginate(signer, []int64{1, 2}, 2, "scope", key)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, items)
+	require.Empty(t, next)
+
+	// The extra row is dropped, and the cursor points at the last row returned
+	items, next, err = Paginate(signer, []int64{1, 2, 3}, 2, "scope", key)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, items)
+
+	c, err := signer.Decode(next, "scope")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), c.ID)
+	require.True(t, createdAt.Equal(c.CreatedAt))
+}
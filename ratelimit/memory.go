@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Full buckets are dropped every this many calls, so keys which aren't seen anymore don't pile up
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // When the bucket will be full again, after which it's the same as a new one
+}
+
+// MemoryStore keeps the buckets in the memory of the process, so every instance of the app has its own
+type MemoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	capacity := float64(limit.Burst)
+	perToken := limit.Interval / time.Duration(limit.Burst)
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		store.buckets[key] = b
+	}
+
+	// Refill the bucket for the time since the last call
+	b.tokens += float64(now.Sub(b.last)) / float64(perToken)
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: time.Duration((1 - b.tokens) * float64(perToken))}, nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((capacity - b.tokens) * float64(perToken)))
+	return Result{Allowed: true}, nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	store.calls++
+	if store.calls < sweepEvery {
+		return
+	}
+	store.calls = 0
+
+	for key, b := range store.buckets {
+		if !now.Before(b.full) {
+			delete(store.buckets, key)
+		}
+	}
+}
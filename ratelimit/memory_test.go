@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryStore() (*MemoryStore, *time.Time) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store, now := newTestMemoryStore()
+	limit := Limit{Burst: 3, Interval: 3 * time.Second}
+
+	// The burst goes thru at once
+	for i := 0; i < limit.Burst; i++ {
+		result, err := store.Take(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// Other keys have their own bucket
+	result, err = store.Take(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// One token comes back every Interval / Burst
+	*now = now.Add(500 * time.Millisecond)
+	result, err = store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	*now = now.Add(500 * time.Millisecond)
+	result, err = store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, err = store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
+
+func TestMemoryStoreUnlimited(t *testing.T) {
+	store, _ := newTestMemoryStore()
+
+	for i := 0; i < 10; i++ {
+		result, err := store.Take(context.Background(), "key", Limit{})
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	require.Empty(t, store.buckets)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store, now := newTestMemoryStore()
+	limit := Limit{Burst: 1, Interval: time.Second}
+
+	for i := 0; i < sweepEvery-1; i++ {
+		_, err := store.Take(context.Background(), fmt.Sprintf("key%d", i), limit)
+		require.NoError(t, err)
+	}
+	require.Len(t, store.buckets, sweepEvery-1)
+
+	// Once their buckets are full again, keys are forgotten
+	*now = now.Add(limit.Interval)
+	_, err := store.Take(context.Background(), "last", limit)
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+}
@@ -0,0 +1,31 @@
+// Package ratelimit throttles callers with token buckets, e.g. to slow down password guessing
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit lets Burst calls thru at once, and one more every Interval / Burst after that,
+// i.e. Burst calls per Interval in the long run. A Burst of 0 means no limit.
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+func (limit Limit) Unlimited() bool {
+	return limit.Burst <= 0 || limit.Interval <= 0
+}
+
+// Result tells whether a call is allowed, and if not, when the next one will be
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take must take a token atomically, so stores shared by several instances of the app,
+// like Redis or Postgres, can back it with a single script or statement per call.
+type Store interface {
+	// Take takes a token from the bucket of the key, which is filled up according to the limit
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -2,9 +2,6 @@
 
 set -e
 
-echo "run db migration"
-source /app/app.env
-/app/migrate -path /app/migration -database "$DB_SOURCE" -verbose up
-
+# The app migrates the db itself on startup, see AUTO_MIGRATE
 echo "start the app"
 exec "$@"
\ No newline at end of file
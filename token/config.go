@@ -0,0 +1,16 @@
+package token
+
+import "github.com/kvnyijia/bank-app/util"
+
+// NewMakerFromConfig picks the asymmetric maker when a signing key is configured, and falls back to the symmetric one otherwise
+func NewMakerFromConfig(config util.Config) (Maker, error) {
+	if len(config.TokenSigningKey) == 0 {
+		return NewPasetoMaker(config.TokenSymmetricKey) // Could also be NewJWTMaker()
+	}
+
+	keyRing, err := ParseKeyRing(config.TokenSigningKey, config.TokenVerificationKeys)
+	if err != nil {
+		return nil, err
+	}
+	return NewEdDSAMaker(keyRing)
+}
@@ -0,0 +1,75 @@
+package token
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const keyIDHeader = "kid"
+
+// EdDSAMaker is a JWT maker which signs tokens with Ed25519 keys,
+// so services that only verify tokens never need to hold the private key
+type EdDSAMaker struct {
+	keyRing *KeyRing
+}
+
+func NewEdDSAMaker(keyRing *KeyRing) (Maker, error) {
+	if keyRing == nil {
+		return nil, errors.New("key ring must not be nil")
+	}
+	return &EdDSAMaker{keyRing}, nil
+}
+
+func (maker *EdDSAMaker) CreateToken(arg CreateTokenParams) (string, *Payload, error) {
+	payload, err := NewPayload(arg)
+	if err != nil {
+		return "", payload, err
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	jwtToken.Header[keyIDHeader] = maker.keyRing.signingKeyID // Tells the verifier which public key to use
+
+	token, err := jwtToken.SignedString(maker.keyRing.signingKey)
+	return token, payload, err
+}
+
+func (maker *EdDSAMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		_, ok := token.Method.(*jwt.SigningMethodEd25519)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		keyID, ok := token.Header[keyIDHeader].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		publicKey, ok := maker.keyRing.publicKey(keyID)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return publicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return payload, nil
+}
+
+// KeySet publishes the public keys that verify the tokens of this maker
+func (maker *EdDSAMaker) KeySet() JSONWebKeySet {
+	return maker.keyRing.KeySet()
+}
@@ -0,0 +1,161 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomKeyRing(t *testing.T, keyID string) *KeyRing {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyRing, err := NewKeyRing(keyID, privateKey)
+	require.NoError(t, err)
+	return keyRing
+}
+
+func TestEdDSAMaker(t *testing.T) {
+	maker, err := NewEdDSAMaker(randomKeyRing(t, "key-1"))
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	role := util.DepositorRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(CreateTokenParams{
+		Username: username,
+		Role:     role,
+		Duration: duration,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredEdDSAToken(t *testing.T) {
+	maker, err := NewEdDSAMaker(randomKeyRing(t, "key-1"))
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(CreateTokenParams{
+		Username: util.RandomOwner(),
+		Duration: -time.Minute,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestEdDSAKeyRotation(t *testing.T) {
+	oldRing := randomKeyRing(t, "key-1")
+	oldMaker, err := NewEdDSAMaker(oldRing)
+	require.NoError(t, err)
+
+	token, _, err := oldMaker.CreateToken(CreateTokenParams{
+		Username: util.RandomOwner(),
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+
+	// The new signing key doesn't know the old one yet
+	newRing := randomKeyRing(t, "key-2")
+	newMaker, err := NewEdDSAMaker(newRing)
+	require.NoError(t, err)
+
+	payload, err := newMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	// Once the old public key is in the ring, tokens signed by it are accepted again
+	oldPublicKey, ok := oldRing.publicKey("key-1")
+	require.True(t, ok)
+	err = newRing.AddPublicKey("key-1", oldPublicKey)
+	require.NoError(t, err)
+
+	payload, err = newMaker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	keySet := newMaker.(KeySetProvider).KeySet()
+	require.Len(t, keySet.Keys, 2)
+	require.Equal(t, "key-1", keySet.Keys[0].KeyID)
+	require.Equal(t, "key-2", keySet.Keys[1].KeyID)
+}
+
+func TestInvalidEdDSATokenAlgHS256(t *testing.T) {
+	keyRing := randomKeyRing(t, "key-1")
+	maker, err := NewEdDSAMaker(keyRing)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(CreateTokenParams{
+		Username: util.RandomOwner(),
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+
+	// Signing with the public key as an HMAC secret must not be accepted
+	publicKey, ok := keyRing.publicKey("key-1")
+	require.True(t, ok)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	jwtToken.Header[keyIDHeader] = "key-1"
+	token, err := jwtToken.SignedString([]byte(publicKey))
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestParseKeyRing(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signingKey := fmt.Sprintf("key-2:%s", base64.StdEncoding.EncodeToString(seed))
+	verificationKeys := fmt.Sprintf("key-1:%s", base64.StdEncoding.EncodeToString(publicKey))
+
+	keyRing, err := ParseKeyRing(signingKey, verificationKeys)
+	require.NoError(t, err)
+	require.Equal(t, "key-2", keyRing.signingKeyID)
+	require.Equal(t, ed25519.NewKeyFromSeed(seed), keyRing.signingKey)
+	require.Len(t, keyRing.KeySet().Keys, 2)
+
+	_, err = ParseKeyRing("key-2", "")
+	require.Error(t, err)
+
+	_, err = ParseKeyRing(signingKey, "key-1:invalid")
+	require.Error(t, err)
+
+	_, err = ParseKeyRing(signingKey, fmt.Sprintf("key-2:%s", base64.StdEncoding.EncodeToString(publicKey)))
+	require.Error(t, err)
+}
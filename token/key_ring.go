@@ -0,0 +1,139 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeyRing holds the Ed25519 keys of an EdDSAMaker, identified by their key ID (kid).
+// Only one key signs new tokens, but every public key in the ring is accepted for verification,
+// so a retired signing key keeps verifying the tokens it issued until they expire.
+type KeyRing struct {
+	signingKeyID string
+	signingKey   ed25519.PrivateKey
+	publicKeys   map[string]ed25519.PublicKey
+}
+
+func NewKeyRing(signingKeyID string, signingKey ed25519.PrivateKey) (*KeyRing, error) {
+	if len(signingKeyID) == 0 {
+		return nil, fmt.Errorf("key ID must not be empty")
+	}
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	ring := &KeyRing{
+		signingKeyID: signingKeyID,
+		signingKey:   signingKey,
+		publicKeys:   make(map[string]ed25519.PublicKey),
+	}
+	ring.publicKeys[signingKeyID] = signingKey.Public().(ed25519.PublicKey)
+	return ring, nil
+}
+
+// AddPublicKey registers a key which is only used to verify tokens
+func (ring *KeyRing) AddPublicKey(keyID string, publicKey ed25519.PublicKey) error {
+	if len(keyID) == 0 {
+		return fmt.Errorf("key ID must not be empty")
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size of %s: must be exactly %d bytes", keyID, ed25519.PublicKeySize)
+	}
+	if _, ok := ring.publicKeys[keyID]; ok {
+		return fmt.Errorf("duplicate key ID %s", keyID)
+	}
+
+	ring.publicKeys[keyID] = publicKey
+	return nil
+}
+
+func (ring *KeyRing) publicKey(keyID string) (ed25519.PublicKey, bool) {
+	publicKey, ok := ring.publicKeys[keyID]
+	return publicKey, ok
+}
+
+// ParseKeyRing builds a key ring from its config representation:
+// the signing key as "kid:base64(32-byte seed)" and the verification keys as a comma-separated list of "kid:base64(public key)"
+func ParseKeyRing(signingKey string, verificationKeys string) (*KeyRing, error) {
+	keyID, seed, err := parseKey(signingKey, ed25519.SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+
+	ring, err := NewKeyRing(keyID, ed25519.NewKeyFromSeed(seed))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range strings.Split(verificationKeys, ",") {
+		if len(strings.TrimSpace(key)) == 0 {
+			continue
+		}
+
+		keyID, publicKey, err := parseKey(key, ed25519.PublicKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verification key: %w", err)
+		}
+
+		err = ring.AddPublicKey(keyID, publicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ring, nil
+}
+
+func parseKey(key string, size int) (string, []byte, error) {
+	keyID, encoded, ok := strings.Cut(strings.TrimSpace(key), ":")
+	if !ok || len(keyID) == 0 {
+		return "", nil, fmt.Errorf("key must be in the format kid:base64")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot decode key %s: %w", keyID, err)
+	}
+	if len(data) != size {
+		return "", nil, fmt.Errorf("key %s must be exactly %d bytes", keyID, size)
+	}
+	return keyID, data, nil
+}
+
+// JSONWebKey is the JWK (RFC 8037) representation of an Ed25519 public key
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// KeySet returns all the public keys of the ring, ordered by key ID
+func (ring *KeyRing) KeySet() JSONWebKeySet {
+	keySet := JSONWebKeySet{
+		Keys: make([]JSONWebKey, 0, len(ring.publicKeys)),
+	}
+	for keyID, publicKey := range ring.publicKeys {
+		keySet.Keys = append(keySet.Keys, JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			KeyID:     keyID,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+		})
+	}
+
+	sort.Slice(keySet.Keys, func(i, j int) bool {
+		return keySet.Keys[i].KeyID < keySet.Keys[j].KeyID
+	})
+	return keySet
+}
@@ -1,9 +1,12 @@
 package token
 
-import "time"
-
 type Maker interface {
-	CreateToken(username string, duration time.Duration) (string, error)
+	CreateToken(arg CreateTokenParams) (string, *Payload, error)
 
 	VerifyToken(token string) (*Payload, error)
 }
+
+// KeySetProvider is implemented by makers which sign tokens with public key cryptography
+type KeySetProvider interface {
+	KeySet() JSONWebKeySet
+}
@@ -2,7 +2,6 @@ package token
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/aead/chacha20poly1305"
 	"github.com/o1egl/paseto"
@@ -25,12 +24,13 @@ func NewPasetoMaker(sysmmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) CreateToken(arg CreateTokenParams) (string, *Payload, error) {
+	payload, err := NewPayload(arg)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
-	return maker.paseto.Encrypt(maker.sysmmetricKey, payload, nil) // The last one is optional footer
+	token, err := maker.paseto.Encrypt(maker.sysmmetricKey, payload, nil) // The last one is optional footer
+	return token, payload, err
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
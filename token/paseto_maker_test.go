@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kvnyijia/bank-app/util"
 	"github.com/stretchr/testify/require"
 )
@@ -13,21 +14,29 @@ func TestPasetoMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := util.RandomOwner()
+	role := util.DepositorRole
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(CreateTokenParams{
+		Username: username,
+		Role:     role,
+		Duration: duration,
+	})
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
+	require.Equal(t, payload.ID, payload.SessionID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -36,12 +45,71 @@ func TestExpriedPaestoToken(t *testing.T) {
 	maker, err := NewPasetoMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomOwner(), -time.Minute)
+	token, payload, err := maker.CreateToken(CreateTokenParams{
+		Username: util.RandomOwner(),
+		Duration: -time.Minute,
+	})
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestPasetoTokenWithSession(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	sessionID := uuid.New()
+	token, payload, err := maker.CreateToken(CreateTokenParams{
+		Username:  util.RandomOwner(),
+		SessionID: sessionID,
+		Duration:  time.Minute,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEqual(t, sessionID, payload.ID)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	require.Equal(t, sessionID, payload.SessionID)
+}
+
+func TestPasetoTokenWithPurpose(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(CreateTokenParams{
+		Username: util.RandomOwner(),
+		Duration: time.Minute,
+		Purpose:  PurposeMFAChallenge,
+	})
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, PurposeMFAChallenge, payload.Purpose)
+}
+
+func TestPasetoTokenWithAuthTime(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	authTime := time.Now().Add(-time.Minute)
+	token, _, err := maker.CreateToken(CreateTokenParams{
+		Username: util.RandomOwner(),
+		Duration: time.Minute,
+		AuthTime: authTime,
+		AMR:      []string{AMRPassword, AMROTP},
+	})
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.WithinDuration(t, authTime, payload.AuthTime, time.Second)
+	require.Equal(t, []string{AMRPassword, AMROTP}, payload.AMR)
+}
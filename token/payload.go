@@ -10,24 +10,68 @@ import (
 var ErrExpiredToken = errors.New("token has expired")
 var ErrInvalidToken = errors.New("token is invalid")
 
+// PurposeRefresh marks a refresh token, which can only be exchanged for access tokens of its session
+const PurposeRefresh = "refresh"
+
+// PurposeMFAChallenge marks a token which only proves the password was right, and can only be exchanged for a real
+// login along with a second factor
+const PurposeMFAChallenge = "mfa_challenge"
+
+// Authentication methods of the amr claim, as registered by RFC 8176
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+)
+
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// Empty for access tokens, which are the only ones accepted as bearer tokens
+	Purpose string `json:"purpose,omitempty"`
+	// When & how the user last proved who they are. Renewed tokens keep the ones of their refresh token.
+	AuthTime time.Time `json:"auth_time"`
+	AMR      []string  `json:"amr,omitempty"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// CreateTokenParams contains the claims of a new token
+type CreateTokenParams struct {
+	Username string
+	Role     string
+	// Session the token belongs to. Leave it empty to start a new session identified by the token itself (e.g. a refresh token)
+	SessionID uuid.UUID
+	Duration  time.Duration
+	// Restricts what the token can be used for, e.g. PurposeRefresh
+	Purpose string
+	// Left zero, the token doesn't count as a recent authentication
+	AuthTime time.Time
+	AMR      []string
+}
+
+func NewPayload(arg CreateTokenParams) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
+	sessionID := arg.SessionID
+	if sessionID == uuid.Nil {
+		sessionID = tokenID
+	}
+
 	payload := &Payload{
 		ID:        tokenID,
-		Username:  username,
+		SessionID: sessionID,
+		Username:  arg.Username,
+		Role:      arg.Role,
 		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		ExpiredAt: time.Now().Add(arg.Duration),
+		Purpose:   arg.Purpose,
+		AuthTime:  arg.AuthTime,
+		AMR:       arg.AMR,
 	}
 	return payload, nil
 }
@@ -0,0 +1,32 @@
+package totp
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets when they enroll
+const RecoveryCodeCount = 10
+
+const recoveryCodeSize = 10 // base32 chars, i.e. 50 bits
+
+// GenerateRecoveryCodes makes the codes a user can log in with once each instead of a TOTP code,
+// formatted like xxxxx-xxxxx so they're easy to write down
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize*5/8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to make recovery code: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = code[:recoveryCodeSize/2] + "-" + code[recoveryCodeSize/2:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode drops the dash, spaces & case of a code the user typed in, so it can be hashed & compared
+func NormalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
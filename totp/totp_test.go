@@ -0,0 +1,118 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The SHA-1 test vectors of RFC 6238, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := Code(secret, Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, expected, code)
+	}
+
+	_, err := Code("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	// A clock a period off is fine, but not two
+	_, ok = Validate(secret, code, now.Add(Period))
+	require.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(-2*Period))
+	require.False(t, ok)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	_, ok = Validate(secret, wrong, now)
+	require.False(t, ok)
+	_, ok = Validate(secret, code[:5], now)
+	require.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("bank-app", "alice", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/bank-app:alice", uri.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	require.Equal(t, "bank-app", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+	require.Equal(t, "30", uri.Query().Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		require.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+
+	require.Equal(t, "abcdefghij", NormalizeRecoveryCode(" ABCDE-fghij "))
+	require.Equal(t, NormalizeRecoveryCode(codes[0]), NormalizeRecoveryCode("  "+codes[0]))
+}
+
+func TestCipher(t *testing.T) {
+	_, err := NewCipher("too short")
+	require.Error(t, err)
+
+	cipher, err := NewCipher("12345678901234567890123456789012")
+	require.NoError(t, err)
+
+	sealed1, err := cipher.Seal("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	sealed2, err := cipher.Seal("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	require.NotEqual(t, sealed1, sealed2)
+	require.NotContains(t, sealed1, "JBSWY3DPEHPK3PXP")
+
+	secret, err := cipher.Open(sealed1)
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+
+	// Secrets sealed with another key, or tampered with, don't open
+	other, err := NewCipher("abcdefghijklmnopqrstuvwxyz123456")
+	require.NoError(t, err)
+	_, err = other.Open(sealed1)
+	require.ErrorIs(t, err, ErrInvalidSealedSecret)
+
+	_, err = cipher.Open(sealed1[:len(sealed1)-2] + "AA")
+	require.ErrorIs(t, err, ErrInvalidSealedSecret)
+	_, err = cipher.Open("")
+	require.ErrorIs(t, err, ErrInvalidSealedSecret)
+}
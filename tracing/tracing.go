@@ -0,0 +1,79 @@
+// Package tracing sets up OpenTelemetry, so a req can be followed from the handlers down to every db query
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kvnyijia/bank-app/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+const ServiceName = "bank-app"
+
+// Exporters spans can be sent to
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// NewExporter creates the exporter of the config, or nil if tracing is turned off
+func NewExporter(ctx context.Context, config util.Config) (sdktrace.SpanExporter, error) {
+	switch config.TracingExporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.TracingEndpoint)}
+		if config.TracingInsecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	}
+	return nil, fmt.Errorf("unsupported tracing exporter %q", config.TracingExporter)
+}
+
+// NewProvider creates a provider which sends spans to the exporter in batches. Without an exporter spans are still made,
+// so trace IDs get propagated & logged, but they're dropped.
+func NewProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(newResource()),
+	}
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// NewInMemoryProvider creates a provider which keeps every span in memory as soon as it ends, for tests to inspect
+func NewInMemoryProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(newResource()),
+		sdktrace.WithSyncer(exporter),
+	)
+	return provider, exporter
+}
+
+// SetGlobal makes the provider the one of every instrumented package, and propagates trace contexts in W3C headers
+func SetGlobal(provider *sdktrace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+func newResource() *resource.Resource {
+	return resource.NewSchemaless(semconv.ServiceName(ServiceName))
+}
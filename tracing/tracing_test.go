@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExporter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		exporter      string
+		checkExporter func(t *testing.T, err error, isNil bool)
+	}{
+		{
+			name:     "None",
+			exporter: ExporterNone,
+			checkExporter: func(t *testing.T, err error, isNil bool) {
+				require.NoError(t, err)
+				require.True(t, isNil)
+			},
+		},
+		{
+			name:     "Default",
+			exporter: "",
+			checkExporter: func(t *testing.T, err error, isNil bool) {
+				require.NoError(t, err)
+				require.True(t, isNil)
+			},
+		},
+		{
+			name:     "Stdout",
+			exporter: ExporterStdout,
+			checkExporter: func(t *testing.T, err error, isNil bool) {
+				require.NoError(t, err)
+				require.False(t, isNil)
+			},
+		},
+		{
+			name:     "Unsupported",
+			exporter: "zipkin",
+			checkExporter: func(t *testing.T, err error, isNil bool) {
+				require.EqualError(t, err, `unsupported tracing exporter "zipkin"`)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter, err := NewExporter(context.Background(), util.Config{TracingExporter: tc.exporter})
+			tc.checkExporter(t, err, exporter == nil)
+		})
+	}
+}
+
+func TestNewInMemoryProvider(t *testing.T) {
+	provider, exporter := NewInMemoryProvider()
+
+	_, span := provider.Tracer("test").Start(context.Background(), "work")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "work", spans[0].Name)
+}
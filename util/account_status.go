@@ -0,0 +1,16 @@
+package util
+
+// Only active accounts move money. Frozen ones can be reactivated, closed ones can't.
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+	AccountClosed = "closed"
+)
+
+func IsSupportedAccountStatus(status string) bool {
+	switch status {
+	case AccountActive, AccountFrozen, AccountClosed:
+		return true
+	}
+	return false
+}
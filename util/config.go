@@ -7,11 +7,94 @@ import (
 )
 
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver string `mapstructure:"DB_DRIVER"`
+	DBSource string `mapstructure:"DB_SOURCE"`
+	// Migrations are read from the URL (e.g. file://db/migration) if it's set, and from the ones built into the binary otherwise
+	MigrationURL string `mapstructure:"MIGRATION_URL"`
+	// Applies new migrations on startup. The app refuses to start against a db at another version either way.
+	AutoMigrate   bool   `mapstructure:"AUTO_MIGRATE"`
+	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	// IPs or CIDRs of the proxies in front of the HTTP server & the gateway, whose X-Forwarded-For headers tell the client IP.
+	// Headers of anyone else are ignored, so clients can't make up their IP to get around rate limits.
+	TrustedProxies    []string `mapstructure:"TRUSTED_PROXIES"`
+	GRPCServerAddress string   `mapstructure:"GRPC_SERVER_ADDRESS"`
+	// Serves the gRPC API as JSON over HTTP, with its OpenAPI spec
+	HTTPGatewayAddress string `mapstructure:"HTTP_GATEWAY_ADDRESS"`
+	// Serves /metrics for Prometheus, apart from the public APIs
+	MetricsAddress    string `mapstructure:"METRICS_ADDRESS"`
+	TokenSymmetricKey string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	// Signs the cursors of paginated listings, apart from the token keys. Must be at least 32 characters.
+	CursorSigningKey string        `mapstructure:"CURSOR_SIGNING_KEY"`
+	HTTPReadTimeout  time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	// Logs are JSON unless the format is console, which is easier to read in development
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	// Spans are sent to stdout, to an OTLP collector at the endpoint, or nowhere if the exporter is none
+	TracingExporter string `mapstructure:"TRACING_EXPORTER"`
+	TracingEndpoint string `mapstructure:"TRACING_ENDPOINT"`
+	TracingInsecure bool   `mapstructure:"TRACING_INSECURE"`
+	// Txs aborted by a deadlock or a serialization failure are retried up to the max retries,
+	// waiting about the backoff before the first retry & twice as long before every next one
+	TxMaxRetries   int           `mapstructure:"TX_MAX_RETRIES"`
+	TxRetryBackoff time.Duration `mapstructure:"TX_RETRY_BACKOFF"`
+	// How long in-flight reqs may take to finish once the app is told to stop
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	// Tokens are signed with Ed25519 instead of the symmetric key once a signing key is set
+	TokenSigningKey       string        `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerificationKeys string        `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	AccessTokenDuration   time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration  time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// Each client IP & each username may try to log in this many times per interval. 0 turns the limit off.
+	LoginRateLimitPerIP       int           `mapstructure:"LOGIN_RATE_LIMIT_PER_IP"`
+	LoginRateLimitPerUsername int           `mapstructure:"LOGIN_RATE_LIMIT_PER_USERNAME"`
+	LoginRateLimitInterval    time.Duration `mapstructure:"LOGIN_RATE_LIMIT_INTERVAL"`
+	// Users are locked out after this many failed logins in a row, for twice as long after every further one,
+	// up to the max lockout. 0 turns lockouts off.
+	LoginMaxAttempts int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginLockout     time.Duration `mapstructure:"LOGIN_LOCKOUT"`
+	LoginMaxLockout  time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT"`
+	// TOTP secrets are sealed with the encryption key. Users with TOTP on log in with a password first, which
+	// gets them an MFA challenge token to exchange along with a code within the duration.
+	TOTPEncryptionKey    string        `mapstructure:"TOTP_ENCRYPTION_KEY"`
+	TOTPIssuer           string        `mapstructure:"TOTP_ISSUER"`
+	MFAChallengeDuration time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	// Emails are sent thru the SMTP server, written to files in the mail dir, or only logged if the mailer is log
+	Mailer       string `mapstructure:"MAILER"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	MailDir      string `mapstructure:"MAIL_DIR"`
+	SMTPAddress  string `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	// Password reset emails link to the URL with the token as its token query param. Tokens work once, within the duration.
+	PasswordResetURL           string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetTokenDuration time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	// Verification emails of new users link to the URL, with the id & code of the email as query params.
+	// Codes work within the duration.
+	EmailVerificationURL      string        `mapstructure:"EMAIL_VERIFICATION_URL"`
+	EmailVerificationDuration time.Duration `mapstructure:"EMAIL_VERIFICATION_DURATION"`
+	// The email worker looks for emails to send every interval. Failed sends are retried after the backoff,
+	// twice as long after every attempt, up to the max attempts.
+	EmailWorkerInterval time.Duration `mapstructure:"EMAIL_WORKER_INTERVAL"`
+	EmailRetryBackoff   time.Duration `mapstructure:"EMAIL_RETRY_BACKOFF"`
+	EmailMaxAttempts    int           `mapstructure:"EMAIL_MAX_ATTEMPTS"`
+	// Idempotency keys can be used again after the ttl, and are deleted every cleanup interval once they are.
+	// A request still in progress after the abandon timeout, e.g. the server crashed, can be retried with its key.
+	// It must be longer than the HTTP write timeout.
+	IdempotencyKeyTTL             time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	IdempotencyKeyAbandonTimeout  time.Duration `mapstructure:"IDEMPOTENCY_KEY_ABANDON_TIMEOUT"`
+	IdempotencyKeyCleanupInterval time.Duration `mapstructure:"IDEMPOTENCY_KEY_CLEANUP_INTERVAL"`
+	// Users can only send transfers once they verified their email
+	TransfersRequireVerifiedEmail bool `mapstructure:"TRANSFERS_REQUIRE_VERIFIED_EMAIL"`
+	// Transfers of at least the threshold of their currency, e.g. USD:100000,EUR:90000, need the user to have logged in
+	// or stepped up within the max age. Currencies left out have no threshold.
+	StepUpThresholds string        `mapstructure:"STEP_UP_THRESHOLDS"`
+	StepUpMaxAge     time.Duration `mapstructure:"STEP_UP_MAX_AGE"`
+	// Exchange rates come from the rates service if its URL is set, and from the rates file otherwise
+	FXRatesURL  string        `mapstructure:"FX_RATES_URL"`
+	FXRatesTTL  time.Duration `mapstructure:"FX_RATES_TTL"`
+	FXRatesFile string        `mapstructure:"FX_RATES_FILE"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
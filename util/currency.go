@@ -1,5 +1,11 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -13,3 +19,29 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// ParseCurrencyAmounts parses amounts per currency in the format USD:1000,EUR:900.
+// Currencies left out have no amount.
+func ParseCurrencyAmounts(value string) (map[string]int64, error) {
+	amounts := map[string]int64{}
+	for _, item := range strings.Split(value, ",") {
+		if len(strings.TrimSpace(item)) == 0 {
+			continue
+		}
+
+		currency, amountText, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			return nil, fmt.Errorf("%q must be in the format currency:amount", item)
+		}
+		if !IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("unsupported currency %q", currency)
+		}
+
+		amount, err := strconv.ParseInt(amountText, 10, 64)
+		if err != nil || amount < 1 {
+			return nil, fmt.Errorf("amount of %s must be a positive integer", currency)
+		}
+		amounts[currency] = amount
+	}
+	return amounts, nil
+}
@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrencyAmounts(t *testing.T) {
+	amounts, err := ParseCurrencyAmounts(" USD:1000, EUR:900 ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{USD: 1000, EUR: 900}, amounts)
+
+	amounts, err = ParseCurrencyAmounts("")
+	require.NoError(t, err)
+	require.Empty(t, amounts)
+
+	for _, value := range []string{"USD", "XYZ:100", "USD:abc", "USD:0", "USD:-5"} {
+		_, err := ParseCurrencyAmounts(value)
+		require.Error(t, err, value)
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,3 +18,22 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+var (
+	dummyHashedPasswordOnce sync.Once
+	dummyHashedPassword     []byte
+)
+
+// CheckPasswordOfMissingUser takes as long as CheckPassword & always fails,
+// so a login for a user who doesn't exist can't be told apart by its timing
+func CheckPasswordOfMissingUser(password string) error {
+	dummyHashedPasswordOnce.Do(func() {
+		dummyHashedPassword, _ = bcrypt.GenerateFromPassword([]byte(RandomString(16)), bcrypt.DefaultCost)
+	})
+
+	err := bcrypt.CompareHashAndPassword(dummyHashedPassword, []byte(password))
+	if err == nil {
+		err = bcrypt.ErrMismatchedHashAndPassword
+	}
+	return err
+}
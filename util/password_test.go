@@ -27,3 +27,8 @@ func TestPassword(t *testing.T) {
 	require.NotEmpty(t, hashedPassword2)
 	require.NotEqual(t, hashedPassword, hashedPassword2)
 }
+
+func TestCheckPasswordOfMissingUser(t *testing.T) {
+	err := CheckPasswordOfMissingUser(RandomString(6))
+	require.ErrorIs(t, err, bcrypt.ErrMismatchedHashAndPassword)
+}
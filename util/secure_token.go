@@ -0,0 +1,46 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+const secureTokenSize = 32
+
+// passwordAlphabet leaves out characters which are easily mixed up, like l, 1 & I
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewSecureToken makes a random token to send to a user, e.g. in a password reset link
+func NewSecureToken() (string, error) {
+	b := make([]byte, secureTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to make token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewSecurePassword makes a random password of n characters to hand out to a user, e.g. by an operator
+func NewSecurePassword(n int) (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+
+	b := make([]byte, n)
+	for i := range b {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to make password: %w", err)
+		}
+		b[i] = passwordAlphabet[index.Int64()]
+	}
+	return string(b), nil
+}
+
+// HashSecureToken is what gets stored in place of a token, so a leaked db can't be used to take over users.
+// Tokens are random & long enough that a fast hash is fine, unlike for passwords.
+func HashSecureToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
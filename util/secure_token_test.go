@@ -0,0 +1,36 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureToken(t *testing.T) {
+	token1, err := NewSecureToken()
+	require.NoError(t, err)
+	require.Len(t, token1, 43)
+
+	token2, err := NewSecureToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token1, token2)
+
+	// The hash is the same for the same token only
+	require.Equal(t, HashSecureToken(token1), HashSecureToken(token1))
+	require.NotEqual(t, HashSecureToken(token1), HashSecureToken(token2))
+	require.NotContains(t, HashSecureToken(token1), token1)
+}
+
+func TestSecurePassword(t *testing.T) {
+	password1, err := NewSecurePassword(12)
+	require.NoError(t, err)
+	require.Len(t, password1, 12)
+	for _, c := range password1 {
+		require.True(t, strings.ContainsRune(passwordAlphabet, c))
+	}
+
+	password2, err := NewSecurePassword(12)
+	require.NoError(t, err)
+	require.NotEqual(t, password1, password2)
+}
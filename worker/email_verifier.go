@@ -0,0 +1,158 @@
+// Package worker runs the background jobs of the app, apart from the reqs which queue them
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/mail"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+)
+
+// How long marking an email as sent may take, even once the worker is told to stop
+const markSentTimeout = 5 * time.Second
+
+// EmailVerifier sends the emails signups queue to verify the address of new users,
+// so signing up doesn't wait for the mail server & no email is lost if it's down for a while
+type EmailVerifier struct {
+	config util.Config
+	store  db.Store
+	mailer mail.Mailer
+	logger zerolog.Logger
+}
+
+func NewEmailVerifier(config util.Config, store db.Store, mailer mail.Mailer, logger zerolog.Logger) *EmailVerifier {
+	return &EmailVerifier{
+		config: config,
+		store:  store,
+		mailer: mailer,
+		logger: logger.With().Str("worker", "email_verifier").Logger(),
+	}
+}
+
+// Run sends the emails which are due every interval, until ctx is done
+func (verifier *EmailVerifier) Run(ctx context.Context) error {
+	if verifier.config.EmailWorkerInterval <= 0 {
+		return fmt.Errorf("email worker interval must be positive, got %s", verifier.config.EmailWorkerInterval)
+	}
+
+	ticker := time.NewTicker(verifier.config.EmailWorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		verifier.SendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends every email which is due now & returns how many were sent.
+// An email which fails is left for a later run, and the others are still sent.
+func (verifier *EmailVerifier) SendDue(ctx context.Context) int {
+	sent := 0
+	for ctx.Err() == nil {
+		verifyEmail, err := verifier.sendNext(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if verifyEmail.ID == 0 {
+			// Nothing could be taken, e.g. the db is down, so the next run tries again
+			verifier.logger.Error().Err(err).Msg("cannot take verification email")
+			break
+		}
+		if err != nil {
+			metrics.VerificationEmails.WithLabelValues(metrics.EmailFailed).Inc()
+			verifier.logger.Warn().Err(err).
+				Int64("email_id", verifyEmail.ID).
+				Str(logging.UsernameField, verifyEmail.Username).
+				Int32("send_attempts", verifyEmail.SendAttempts).
+				Msg("cannot send verification email")
+			continue
+		}
+
+		metrics.VerificationEmails.WithLabelValues(metrics.EmailSent).Inc()
+		verifier.logger.Info().
+			Int64("email_id", verifyEmail.ID).
+			Str(logging.UsernameField, verifyEmail.Username).
+			Msg("verification email sent")
+		sent++
+	}
+	return sent
+}
+
+// sendNext takes the next email which is due & sends it with a new code.
+// It returns sql.ErrNoRows if no email is due.
+func (verifier *EmailVerifier) sendNext(ctx context.Context) (db.VerifyEmail, error) {
+	secretCode, err := util.NewSecureToken()
+	if err != nil {
+		return db.VerifyEmail{}, err
+	}
+
+	// Only the hash is stored, the code itself is only ever in the email
+	verifyEmail, err := verifier.store.ClaimVerifyEmail(ctx, db.ClaimVerifyEmailParams{
+		SecretCodeHash: util.HashSecureToken(secretCode),
+		RetrySeconds:   verifier.config.EmailRetryBackoff.Seconds(),
+		ExpireSeconds:  verifier.config.EmailVerificationDuration.Seconds(),
+		MaxAttempts:    int32(verifier.config.EmailMaxAttempts),
+	})
+	if err != nil {
+		return db.VerifyEmail{}, err
+	}
+
+	email, err := verifier.verificationEmail(verifyEmail, secretCode)
+	if err != nil {
+		return verifyEmail, err
+	}
+
+	if err := verifier.mailer.Send(ctx, email); err != nil {
+		return verifyEmail, err
+	}
+
+	// The email is out, so it's marked as sent even if the worker is stopping. Otherwise it'd be sent again
+	// with a new code, and the link in this one would stop working.
+	markCtx, cancel := context.WithTimeout(context.Background(), markSentTimeout)
+	defer cancel()
+	if err := verifier.store.MarkVerifyEmailSent(markCtx, verifyEmail.ID); err != nil {
+		return verifyEmail, fmt.Errorf("email was sent, but cannot mark it as sent: %w", err)
+	}
+	return verifyEmail, nil
+}
+
+func (verifier *EmailVerifier) verificationEmail(verifyEmail db.VerifyEmail, secretCode string) (mail.Email, error) {
+	link, err := url.Parse(verifier.config.EmailVerificationURL)
+	if err != nil {
+		return mail.Email{}, fmt.Errorf("invalid email verification URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("code", secretCode)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf(`Hi %s,
+
+Thanks for signing up! To verify your email, open this link within %s:
+
+%s
+
+If you didn't sign up, you can ignore this email.
+`, verifyEmail.Username, verifier.config.EmailVerificationDuration, link)
+
+	return mail.Email{
+		To:      verifyEmail.Email,
+		Subject: "Verify your email",
+		Body:    body,
+	}, nil
+}
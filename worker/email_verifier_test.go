@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/mail"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMailer keeps the emails instead of sending them, failing for the recipients in failFor
+type recordingMailer struct {
+	emails  []mail.Email
+	failFor map[string]bool
+}
+
+func (mailer *recordingMailer) Send(ctx context.Context, email mail.Email) error {
+	if mailer.failFor[email.To] {
+		return errors.New("mail server down")
+	}
+	mailer.emails = append(mailer.emails, email)
+	return nil
+}
+
+func newTestVerifier(store db.Store, mailer mail.Mailer) *EmailVerifier {
+	config := util.Config{
+		EmailVerificationURL:      "https://bank.example.com/users/verify_email",
+		EmailVerificationDuration: 24 * time.Hour,
+		EmailWorkerInterval:       time.Second,
+		EmailRetryBackoff:         30 * time.Second,
+		EmailMaxAttempts:          5,
+	}
+	return NewEmailVerifier(config, store, mailer, zerolog.Nop())
+}
+
+func randomVerifyEmail() db.VerifyEmail {
+	return db.VerifyEmail{
+		ID:       util.RandomInt(1, 1000),
+		Username: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+}
+
+// claimStub makes the store hand out the emails in order, then no more
+func claimStub(t *testing.T, store *mockdb.MockStore, emails ...db.VerifyEmail) *[]string {
+	codeHashes := []string{}
+	calls := make([]*gomock.Call, 0, len(emails)+1)
+	for _, email := range emails {
+		email := email
+		calls = append(calls, store.EXPECT().
+			ClaimVerifyEmail(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, arg db.ClaimVerifyEmailParams) (db.VerifyEmail, error) {
+				require.Equal(t, float64(30), arg.RetrySeconds)
+				require.Equal(t, float64(24*60*60), arg.ExpireSeconds)
+				require.Equal(t, int32(5), arg.MaxAttempts)
+				codeHashes = append(codeHashes, arg.SecretCodeHash)
+				email.SecretCodeHash = arg.SecretCodeHash
+				return email, nil
+			}))
+	}
+	calls = append(calls, store.EXPECT().
+		ClaimVerifyEmail(gomock.Any(), gomock.Any()).
+		Return(db.VerifyEmail{}, sql.ErrNoRows))
+	gomock.InOrder(calls...)
+	return &codeHashes
+}
+
+func TestSendDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	verifyEmail1 := randomVerifyEmail()
+	verifyEmail2 := randomVerifyEmail()
+
+	store := mockdb.NewMockStore(ctrl)
+	codeHashes := claimStub(t, store, verifyEmail1, verifyEmail2)
+	store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Eq(verifyEmail1.ID)).Times(1)
+	store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Eq(verifyEmail2.ID)).Times(1)
+
+	mailer := &recordingMailer{}
+	sent := newTestVerifier(store, mailer).SendDue(context.Background())
+	require.Equal(t, 2, sent)
+	require.Len(t, mailer.emails, 2)
+
+	for i, verifyEmail := range []db.VerifyEmail{verifyEmail1, verifyEmail2} {
+		email := mailer.emails[i]
+		require.Equal(t, verifyEmail.Email, email.To)
+
+		// The link has the id of the email & the code, of which only the hash is stored
+		link, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(email.Body))
+		require.NoError(t, err)
+		require.Equal(t, "/users/verify_email", link.Path)
+		require.Equal(t, strconv.FormatInt(verifyEmail.ID, 10), link.Query().Get("id"))
+		require.Equal(t, (*codeHashes)[i], util.HashSecureToken(link.Query().Get("code")))
+	}
+}
+
+func TestSendDueMailerError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	failing := randomVerifyEmail()
+	working := randomVerifyEmail()
+
+	// The failed email isn't marked as sent, so it's retried later, and the next one is still sent
+	store := mockdb.NewMockStore(ctrl)
+	claimStub(t, store, failing, working)
+	store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Eq(failing.ID)).Times(0)
+	store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Eq(working.ID)).Times(1)
+
+	mailer := &recordingMailer{failFor: map[string]bool{failing.Email: true}}
+	sent := newTestVerifier(store, mailer).SendDue(context.Background())
+	require.Equal(t, 1, sent)
+	require.Len(t, mailer.emails, 1)
+	require.Equal(t, working.Email, mailer.emails[0].To)
+}
+
+func TestSendDueDBError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimVerifyEmail(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.VerifyEmail{}, sql.ErrConnDone)
+
+	mailer := &recordingMailer{}
+	sent := newTestVerifier(store, mailer).SendDue(context.Background())
+	require.Zero(t, sent)
+	require.Empty(t, mailer.emails)
+}
+
+func TestRunStops(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimVerifyEmail(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.VerifyEmail{}, sql.ErrNoRows)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- newTestVerifier(store, &recordingMailer{}).Run(ctx)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+
+	err := NewEmailVerifier(util.Config{}, store, &recordingMailer{}, zerolog.Nop()).Run(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+)
+
+// IdempotencyKeyCleaner deletes the idempotency keys which expired, so the stored responses don't pile up
+type IdempotencyKeyCleaner struct {
+	config util.Config
+	store  db.Store
+	logger zerolog.Logger
+}
+
+func NewIdempotencyKeyCleaner(config util.Config, store db.Store, logger zerolog.Logger) *IdempotencyKeyCleaner {
+	return &IdempotencyKeyCleaner{
+		config: config,
+		store:  store,
+		logger: logger.With().Str("worker", "idempotency_key_cleaner").Logger(),
+	}
+}
+
+// Run deletes the expired keys every cleanup interval, until ctx is done
+func (cleaner *IdempotencyKeyCleaner) Run(ctx context.Context) error {
+	if cleaner.config.IdempotencyKeyCleanupInterval <= 0 {
+		return fmt.Errorf("idempotency key cleanup interval must be positive, got %s", cleaner.config.IdempotencyKeyCleanupInterval)
+	}
+
+	ticker := time.NewTicker(cleaner.config.IdempotencyKeyCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		cleaner.DeleteExpired(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteExpired deletes the keys older than the ttl & returns how many were deleted.
+// A failure is only logged, the next run tries again.
+func (cleaner *IdempotencyKeyCleaner) DeleteExpired(ctx context.Context) int64 {
+	deleted, err := cleaner.store.DeleteExpiredIdempotencyKeys(ctx, time.Now().Add(-cleaner.config.IdempotencyKeyTTL))
+	if err != nil {
+		cleaner.logger.Error().Err(err).Msg("cannot delete expired idempotency keys")
+		return 0
+	}
+
+	if deleted > 0 {
+		cleaner.logger.Info().Int64("deleted", deleted).Msg("expired idempotency keys deleted")
+	}
+	return deleted
+}
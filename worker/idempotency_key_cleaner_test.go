@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCleaner(store *mockdb.MockStore) *IdempotencyKeyCleaner {
+	config := util.Config{
+		IdempotencyKeyTTL:             24 * time.Hour,
+		IdempotencyKeyCleanupInterval: time.Hour,
+	}
+	return NewIdempotencyKeyCleaner(config, store, zerolog.Nop())
+}
+
+func TestDeleteExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredIdempotencyKeys(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, createdBefore time.Time) (int64, error) {
+			require.WithinDuration(t, time.Now().Add(-24*time.Hour), createdBefore, time.Second)
+			return 3, nil
+		})
+
+	deleted := newTestCleaner(store).DeleteExpired(context.Background())
+	require.Equal(t, int64(3), deleted)
+}
+
+func TestDeleteExpiredDBError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredIdempotencyKeys(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(0), sql.ErrConnDone)
+
+	deleted := newTestCleaner(store).DeleteExpired(context.Background())
+	require.Zero(t, deleted)
+}
+
+func TestCleanerRunStops(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredIdempotencyKeys(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(int64(0), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- newTestCleaner(store).Run(ctx)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+
+	err := NewIdempotencyKeyCleaner(util.Config{}, store, zerolog.Nop()).Run(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/logging"
+	"github.com/kvnyijia/bank-app/mail"
+	"github.com/kvnyijia/bank-app/metrics"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+)
+
+// errNoUser is returned by sendNext when no user has the email, so there is nothing to send
+var errNoUser = errors.New("no user has the email")
+
+// PasswordResetSender sends the emails forgot password reqs queue. The req only queues the email,
+// so neither its answer nor how long it takes tells whether a user has the email.
+type PasswordResetSender struct {
+	config util.Config
+	store  db.Store
+	mailer mail.Mailer
+	logger zerolog.Logger
+}
+
+func NewPasswordResetSender(config util.Config, store db.Store, mailer mail.Mailer, logger zerolog.Logger) *PasswordResetSender {
+	return &PasswordResetSender{
+		config: config,
+		store:  store,
+		mailer: mailer,
+		logger: logger.With().Str("worker", "password_reset_sender").Logger(),
+	}
+}
+
+// Run sends the emails which are due every interval, until ctx is done
+func (sender *PasswordResetSender) Run(ctx context.Context) error {
+	if sender.config.EmailWorkerInterval <= 0 {
+		return fmt.Errorf("email worker interval must be positive, got %s", sender.config.EmailWorkerInterval)
+	}
+
+	ticker := time.NewTicker(sender.config.EmailWorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		sender.SendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends every email which is due now & returns how many were sent.
+// An email which fails is left for a later run, and the others are still sent.
+func (sender *PasswordResetSender) SendDue(ctx context.Context) int {
+	sent := 0
+	for ctx.Err() == nil {
+		resetEmail, user, err := sender.sendNext(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if resetEmail.ID == 0 {
+			// Nothing could be taken, e.g. the db is down, so the next run tries again
+			sender.logger.Error().Err(err).Msg("cannot take password reset email")
+			break
+		}
+		if errors.Is(err, errNoUser) {
+			metrics.PasswordResetEmails.WithLabelValues(metrics.EmailNoUser).Inc()
+			continue
+		}
+		if err != nil {
+			metrics.PasswordResetEmails.WithLabelValues(metrics.EmailFailed).Inc()
+			sender.logger.Warn().Err(err).
+				Int64("email_id", resetEmail.ID).
+				Str(logging.UsernameField, user.Username).
+				Int32("send_attempts", resetEmail.SendAttempts).
+				Msg("cannot send password reset email")
+			continue
+		}
+
+		metrics.PasswordResetEmails.WithLabelValues(metrics.EmailSent).Inc()
+		sender.logger.Info().
+			Int64("email_id", resetEmail.ID).
+			Str(logging.UsernameField, user.Username).
+			Msg("password reset email sent")
+		sent++
+	}
+	return sent
+}
+
+// sendNext takes the next email which is due & sends it with a new single-use reset token.
+// It returns sql.ErrNoRows if no email is due, and errNoUser if no user has the email.
+func (sender *PasswordResetSender) sendNext(ctx context.Context) (db.PasswordResetEmail, db.User, error) {
+	resetEmail, err := sender.store.ClaimPasswordResetEmail(ctx, db.ClaimPasswordResetEmailParams{
+		RetrySeconds: sender.config.EmailRetryBackoff.Seconds(),
+		MaxAttempts:  int32(sender.config.EmailMaxAttempts),
+	})
+	if err != nil {
+		return db.PasswordResetEmail{}, db.User{}, err
+	}
+
+	user, err := sender.store.GetUserByEmail(ctx, resetEmail.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := sender.markSent(resetEmail); err != nil {
+				return resetEmail, user, err
+			}
+			return resetEmail, user, errNoUser
+		}
+		return resetEmail, user, err
+	}
+
+	resetToken, err := util.NewSecureToken()
+	if err != nil {
+		return resetEmail, user, err
+	}
+
+	// Only the hash is stored, the token itself is only ever in the email.
+	// If the send fails, the token is never used & a retry makes a new one.
+	_, err = sender.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		TokenHash: util.HashSecureToken(resetToken),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(sender.config.PasswordResetTokenDuration),
+	})
+	if err != nil {
+		return resetEmail, user, err
+	}
+
+	email, err := sender.passwordResetEmail(user, resetToken)
+	if err != nil {
+		return resetEmail, user, err
+	}
+
+	if err := sender.mailer.Send(ctx, email); err != nil {
+		return resetEmail, user, err
+	}
+	return resetEmail, user, sender.markSent(resetEmail)
+}
+
+// markSent marks the email as sent even if the worker is stopping, so the user doesn't get another one
+func (sender *PasswordResetSender) markSent(resetEmail db.PasswordResetEmail) error {
+	markCtx, cancel := context.WithTimeout(context.Background(), markSentTimeout)
+	defer cancel()
+	if err := sender.store.MarkPasswordResetEmailSent(markCtx, resetEmail.ID); err != nil {
+		return fmt.Errorf("cannot mark password reset email as sent: %w", err)
+	}
+	return nil
+}
+
+func (sender *PasswordResetSender) passwordResetEmail(user db.User, resetToken string) (mail.Email, error) {
+	link, err := url.Parse(sender.config.PasswordResetURL)
+	if err != nil {
+		return mail.Email{}, fmt.Errorf("invalid password reset URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", resetToken)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf(`Hi %s,
+
+Someone asked to reset the password of your account %s. To choose a new password, open this link within %s:
+
+%s
+
+If it wasn't you, you can ignore this email. Your password stays as it is.
+`, user.FullName, user.Username, sender.config.PasswordResetTokenDuration, link)
+
+	return mail.Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kvnyijia/bank-app/db/mock"
+	db "github.com/kvnyijia/bank-app/db/sqlc"
+	"github.com/kvnyijia/bank-app/mail"
+	"github.com/kvnyijia/bank-app/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResetSender(store db.Store, mailer mail.Mailer) *PasswordResetSender {
+	config := util.Config{
+		PasswordResetURL:           "https://bank.example.com/reset-password",
+		PasswordResetTokenDuration: 30 * time.Minute,
+		EmailWorkerInterval:        time.Second,
+		EmailRetryBackoff:          30 * time.Second,
+		EmailMaxAttempts:           5,
+	}
+	return NewPasswordResetSender(config, store, mailer, zerolog.Nop())
+}
+
+func randomResetUser() db.User {
+	return db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+}
+
+// claimResetStub makes the store hand out emails to the addresses in order, then no more
+func claimResetStub(t *testing.T, store *mockdb.MockStore, emails ...string) []db.PasswordResetEmail {
+	resetEmails := make([]db.PasswordResetEmail, 0, len(emails))
+	calls := make([]*gomock.Call, 0, len(emails)+1)
+	for _, email := range emails {
+		resetEmail := db.PasswordResetEmail{ID: util.RandomInt(1, 1000), Email: email, SendAttempts: 1}
+		resetEmails = append(resetEmails, resetEmail)
+		calls = append(calls, store.EXPECT().
+			ClaimPasswordResetEmail(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, arg db.ClaimPasswordResetEmailParams) (db.PasswordResetEmail, error) {
+				require.Equal(t, float64(30), arg.RetrySeconds)
+				require.Equal(t, int32(5), arg.MaxAttempts)
+				return resetEmail, nil
+			}))
+	}
+	calls = append(calls, store.EXPECT().
+		ClaimPasswordResetEmail(gomock.Any(), gomock.Any()).
+		Return(db.PasswordResetEmail{}, sql.ErrNoRows))
+	gomock.InOrder(calls...)
+	return resetEmails
+}
+
+func TestPasswordResetSendDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := randomResetUser()
+	unknownEmail := util.RandomEmail()
+
+	store := mockdb.NewMockStore(ctrl)
+	resetEmails := claimResetStub(t, store, user.Email, unknownEmail)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(unknownEmail)).Times(1).Return(db.User{}, sql.ErrNoRows)
+
+	var tokenHash string
+	store.EXPECT().
+		CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.WithinDuration(t, time.Now().Add(30*time.Minute), arg.ExpiresAt, time.Second)
+			tokenHash = arg.TokenHash
+			return db.PasswordResetToken{TokenHash: arg.TokenHash, Username: arg.Username}, nil
+		})
+
+	// Emails of no user are done as well, with nothing sent
+	store.EXPECT().MarkPasswordResetEmailSent(gomock.Any(), gomock.Eq(resetEmails[0].ID)).Times(1)
+	store.EXPECT().MarkPasswordResetEmailSent(gomock.Any(), gomock.Eq(resetEmails[1].ID)).Times(1)
+
+	mailer := &recordingMailer{}
+	sent := newTestResetSender(store, mailer).SendDue(context.Background())
+	require.Equal(t, 1, sent)
+	require.Len(t, mailer.emails, 1)
+	require.Equal(t, user.Email, mailer.emails[0].To)
+
+	// The email links to the reset page with the token, of which only the hash is stored
+	link, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(mailer.emails[0].Body))
+	require.NoError(t, err)
+	require.Equal(t, "/reset-password", link.Path)
+	resetToken := link.Query().Get("token")
+	require.NotEmpty(t, resetToken)
+	require.Equal(t, tokenHash, util.HashSecureToken(resetToken))
+}
+
+func TestPasswordResetSendDueMailerError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	failing := randomResetUser()
+	working := randomResetUser()
+
+	// The failed email isn't marked as sent, so it's retried later, and the next one is still sent
+	store := mockdb.NewMockStore(ctrl)
+	resetEmails := claimResetStub(t, store, failing.Email, working.Email)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(failing.Email)).Times(1).Return(failing, nil)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(working.Email)).Times(1).Return(working, nil)
+	store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(2)
+	store.EXPECT().MarkPasswordResetEmailSent(gomock.Any(), gomock.Eq(resetEmails[0].ID)).Times(0)
+	store.EXPECT().MarkPasswordResetEmailSent(gomock.Any(), gomock.Eq(resetEmails[1].ID)).Times(1)
+
+	mailer := &recordingMailer{failFor: map[string]bool{failing.Email: true}}
+	sent := newTestResetSender(store, mailer).SendDue(context.Background())
+	require.Equal(t, 1, sent)
+	require.Len(t, mailer.emails, 1)
+	require.Equal(t, working.Email, mailer.emails[0].To)
+}
+
+func TestPasswordResetSendDueDBError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimPasswordResetEmail(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.PasswordResetEmail{}, sql.ErrConnDone)
+
+	mailer := &recordingMailer{}
+	sent := newTestResetSender(store, mailer).SendDue(context.Background())
+	require.Zero(t, sent)
+	require.Empty(t, mailer.emails)
+}